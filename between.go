@@ -0,0 +1,292 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// BetweenOptions adjust how BetweenWith() scans for delimiters. The zero value behaves as Between().
+type BetweenOptions struct {
+	// Pos is the position of the start of the buffer, errors are reported relative to it
+	Pos Position
+	// Recover returns a partial result along with the error instead of giving up, an unexpected
+	// closing delimiter is skipped, an escape at the end of input is kept and an unterminated
+	// region is closed at the end of input. All the problems found are joined into the error.
+	Recover bool
+	// Flat turns off nesting, an opening delimiter inside the region is treated as content
+	// and the first closing delimiter ends the region
+	Flat bool
+	// Inclusive keeps the outer opening and closing delimiters in the result
+	Inclusive bool
+	// Quotes are delimiters (e.g. `"` and `'`) that suspend delimiter matching inside the region
+	// until the same quote is seen again, the escape value is honoured inside quotes
+	Quotes [][]byte
+	// DoubledClose treats a closing delimiter that is immediately repeated as content, as SQL
+	// strings do, so the region of 'it''s' holds it''s for SQLUnescape to decode
+	DoubledClose bool
+	// Unescape, if set, decodes the escape sequences of the result (e.g. CUnescape), an
+	// *EscapeError is returned with the position of a malformed sequence
+	Unescape Unescaper
+	// Limits bound the bytes scanned (MaxBytes), the nesting depth (MaxDepth) and the size of
+	// the result (MaxTokenSize), exceeding them returns a *LimitError
+	Limits Limits
+	// Context, if set, is checked while scanning, scanning stops with its error once it is done
+	Context context.Context
+}
+
+// Between is like the package level Between() using the Lexer's character classes
+func (l *Lexer) Between(openValue []byte, closeValue []byte, escapeValue []byte, buf []byte) ([]byte, []byte, error) {
+	return l.BetweenWith(openValue, closeValue, escapeValue, buf, BetweenOptions{})
+}
+
+// BetweenAt is like Between where buf starts at pos
+func (l *Lexer) BetweenAt(openValue []byte, closeValue []byte, escapeValue []byte, buf []byte, pos Position) ([]byte, []byte, error) {
+	return l.BetweenWith(openValue, closeValue, escapeValue, buf, BetweenOptions{Pos: pos})
+}
+
+// BetweenWith is like Between adjusted by opts. Delimiters and the escape value may be more
+// than one byte long (e.g. "{{" and "}}", "<!--" and "-->" or `"""`). Errors are a *MissingOpenError,
+// *UnterminatedError, *UnexpectedCloseError, *EscapeAtEndError or *LimitError, use errors.As() to inspect them.
+func (l *Lexer) BetweenWith(openValue []byte, closeValue []byte, escapeValue []byte, buf []byte, opts BetweenOptions) ([]byte, []byte, error) {
+	var (
+		between []byte
+		openPos Position
+		errs    []error
+		quoted  []byte
+	)
+	if len(openValue) == 0 || len(closeValue) == 0 {
+		return nil, buf, fmt.Errorf("missing delimiter, open %q, close %q", openValue, closeValue)
+	}
+	isQuote := bytes.Equal(openValue, closeValue)
+	nest := isQuote == false && opts.Flat == false
+	hasEscapeValue := len(escapeValue) > 0
+	depth := 0
+	pos := opts.Pos.orStart()
+	size := len(buf)
+
+	// advance consumes n bytes of buf returning them
+	advance := func(n int) []byte {
+		value := buf[0:n]
+		buf = buf[n:]
+		pos = pos.Advance(value)
+		return value
+	}
+	// codePoint consumes the next UTF-8 code point
+	codePoint := func() []byte {
+		_, size := utf8.DecodeRune(buf)
+		return advance(size)
+	}
+	// add appends value to the result unless that would pass MaxTokenSize, then overflow
+	// records the size the result would have had and nothing more is added
+	overflow := 0
+	add := func(value []byte) {
+		if overflow > 0 {
+			return
+		}
+		if max := opts.Limits.MaxTokenSize; max > 0 && len(between)+len(value) > max {
+			overflow = len(between) + len(value)
+			return
+		}
+		between = append(between[:], value[:]...)
+	}
+	// keep adds value to the result when inside the region
+	keep := func(value []byte) {
+		if depth > 0 {
+			add(value)
+		}
+	}
+	// result returns between and any problems recovered from
+	result := func() ([]byte, []byte, error) {
+		if opts.Unescape != nil {
+			unescaped, err := opts.Unescape(between)
+			if err != nil {
+				var escapeErr *EscapeError
+				if errors.As(err, &escapeErr) == true {
+					start := openPos
+					if opts.Inclusive == false {
+						start = start.Advance(openValue)
+					}
+					escapeErr.Pos = start.Advance(between[0:escapeErr.Offset])
+				}
+				if opts.Recover == false {
+					return nil, buf, err
+				}
+				errs = append(errs, err)
+			} else {
+				between = unescaped
+			}
+		}
+		if len(errs) > 0 {
+			return between, buf, errors.Join(errs...)
+		}
+		return between, buf, nil
+	}
+
+	for steps := 1; ; steps++ {
+		resultSize := len(between)
+		if overflow > 0 {
+			resultSize = overflow
+		}
+		if err := betweenLimits(opts, depth, size-len(buf), resultSize, steps, pos); err != nil {
+			if opts.Recover == false {
+				return nil, buf, err
+			}
+			errs = append(errs, err)
+			return result()
+		}
+		if len(buf) == 0 {
+			var err error
+			if depth == 0 {
+				err = &MissingOpenError{Open: openValue, Pos: pos}
+			} else {
+				err = &UnterminatedError{Open: openValue, Close: closeValue, OpenPos: openPos, Pos: pos, Depth: depth}
+			}
+			if opts.Recover == false {
+				return nil, buf, err
+			}
+			errs = append(errs, err)
+			return result()
+		}
+		if quoted != nil {
+			switch {
+			case hasEscapeValue == true && bytes.HasPrefix(buf, escapeValue):
+				escapePos := pos
+				escape := advance(len(escapeValue))
+				if len(buf) == 0 {
+					err := &EscapeAtEndError{Escape: escapeValue, OpenPos: openPos, Pos: escapePos, Depth: depth}
+					if opts.Recover == false {
+						return nil, buf, err
+					}
+					errs = append(errs, err)
+					keep(escape)
+					continue
+				}
+				keep(escape)
+				keep(codePoint())
+			case bytes.HasPrefix(buf, quoted):
+				keep(advance(len(quoted)))
+				quoted = nil
+			default:
+				keep(codePoint())
+			}
+			continue
+		}
+		quote := matchAny(buf, opts.Quotes)
+		switch {
+		case hasEscapeValue == true && bytes.HasPrefix(buf, escapeValue):
+			escapePos := pos
+			escape := advance(len(escapeValue))
+			if len(buf) == 0 {
+				err := &EscapeAtEndError{Escape: escapeValue, OpenPos: openPos, Pos: escapePos, Depth: depth}
+				if opts.Recover == false {
+					return nil, buf, err
+				}
+				errs = append(errs, err)
+				keep(escape)
+				continue
+			}
+			// An escaped delimiter outside of the region can't open it
+			keep(escape)
+			keep(codePoint())
+		case depth > 0 && opts.DoubledClose == true && bytes.HasPrefix(buf, closeValue) && bytes.HasPrefix(buf[len(closeValue):], closeValue):
+			keep(advance(len(closeValue) * 2))
+		case depth > 0 && bytes.HasPrefix(buf, closeValue):
+			value := advance(len(closeValue))
+			depth--
+			if depth == 0 {
+				if opts.Inclusive == true {
+					add(value)
+				}
+				// An overflowing result is reported at the top of the loop
+				if overflow == 0 {
+					return result()
+				}
+				continue
+			}
+			keep(value)
+		case (depth == 0 || nest == true) && bytes.HasPrefix(buf, openValue):
+			if depth == 0 {
+				openPos = pos
+			}
+			value := advance(len(openValue))
+			if depth > 0 || opts.Inclusive == true {
+				add(value)
+			}
+			depth++
+		case depth == 0 && bytes.HasPrefix(buf, closeValue):
+			err := &UnexpectedCloseError{Close: closeValue, Pos: pos}
+			if opts.Recover == false {
+				return nil, buf, err
+			}
+			errs = append(errs, err)
+			advance(len(closeValue))
+		case depth > 0 && quote != nil:
+			// Delimiters are not matched inside a quote
+			keep(advance(len(quote)))
+			quoted = quote
+		default:
+			keep(codePoint())
+		}
+	}
+}
+
+// betweenLimits checks the scanning done so far by BetweenWith against opts.Limits and opts.Context
+func betweenLimits(opts BetweenOptions, depth int, consumed int, size int, steps int, pos Position) error {
+	if opts.Context != nil && steps%contextInterval == 0 {
+		if err := opts.Context.Err(); err != nil {
+			return err
+		}
+	}
+	if err := opts.Limits.check("MaxBytes", opts.Limits.MaxBytes, consumed, pos); err != nil {
+		return err
+	}
+	if err := opts.Limits.check("MaxDepth", opts.Limits.MaxDepth, depth, pos); err != nil {
+		return err
+	}
+	return opts.Limits.check("MaxTokenSize", opts.Limits.MaxTokenSize, size, pos)
+}
+
+// matchAny returns the first of values that prefixes buf or nil
+func matchAny(buf []byte, values [][]byte) []byte {
+	for _, value := range values {
+		if len(value) > 0 && bytes.HasPrefix(buf, value) {
+			return value
+		}
+	}
+	return nil
+}
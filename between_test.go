@@ -0,0 +1,173 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBetweenErrors(t *testing.T) {
+	open, close, escape := []byte("{"), []byte("}"), []byte("\\")
+
+	between, _, err := Between(open, close, escape, []byte("a\n {b {c} d"))
+	var unterminated *UnterminatedError
+	if errors.As(err, &unterminated) == false {
+		t.Errorf("expected *UnterminatedError, found %v", err)
+	} else if unterminated.OpenPos.Line != 2 || unterminated.OpenPos.Column != 2 || unterminated.Depth != 1 {
+		t.Errorf("expected { opened at 2:2 with depth 1, found %s depth %d", unterminated.OpenPos, unterminated.Depth)
+	}
+	if between != nil {
+		t.Errorf("expected no result without recovery, found [%s]", between)
+	}
+
+	_, _, err = Between(open, close, escape, []byte("a } {b}"))
+	var unexpected *UnexpectedCloseError
+	if errors.As(err, &unexpected) == false {
+		t.Errorf("expected *UnexpectedCloseError, found %v", err)
+	} else if unexpected.Pos.Column != 3 {
+		t.Errorf("expected } at 1:3, found %s", unexpected.Pos)
+	}
+
+	_, _, err = Between(open, close, escape, []byte("{ab\\"))
+	var escapeAtEnd *EscapeAtEndError
+	if errors.As(err, &escapeAtEnd) == false {
+		t.Errorf("expected *EscapeAtEndError, found %v", err)
+	} else if escapeAtEnd.Pos.Column != 4 || escapeAtEnd.OpenPos.Column != 1 || escapeAtEnd.Depth != 1 {
+		t.Errorf("expected escape at 1:4 in { at 1:1, found %s in %s", escapeAtEnd.Pos, escapeAtEnd.OpenPos)
+	}
+
+	_, _, err = Between(open, close, escape, []byte("no delimiters"))
+	var missingOpen *MissingOpenError
+	if errors.As(err, &missingOpen) == false {
+		t.Errorf("expected *MissingOpenError, found %v", err)
+	}
+
+	// An escaped delimiter is kept in the region and can't open or close it
+	between, rest, err := Between(open, close, escape, []byte(`\{ {a\}b} c`))
+	if err != nil || string(between) != `a\}b` || string(rest) != " c" {
+		t.Errorf("expected [a\\}b] and [ c], found [%s] [%s] %v", between, rest, err)
+	}
+}
+
+func TestBetweenRecover(t *testing.T) {
+	opts := BetweenOptions{Recover: true}
+	between, rest, err := BetweenWith([]byte("("), []byte(")"), []byte(""), []byte("x) (a (b) c"), opts)
+	if string(between) != "a (b) c" || len(rest) != 0 {
+		t.Errorf("expected the partial result [a (b) c], found [%s] [%s]", between, rest)
+	}
+	var (
+		unterminated *UnterminatedError
+		unexpected   *UnexpectedCloseError
+	)
+	if errors.As(err, &unterminated) == false || errors.As(err, &unexpected) == false {
+		t.Errorf("expected both an unexpected close and unterminated diagnostic, found %v", err)
+	}
+	if unterminated != nil && unterminated.OpenPos.Column != 4 {
+		t.Errorf("expected ( opened at 1:4, found %s", unterminated.OpenPos)
+	}
+
+	between, _, err = BetweenWith([]byte(`"`), []byte(`"`), []byte(`\`), []byte(`say "hi\`), opts)
+	var escapeAtEnd *EscapeAtEndError
+	if string(between) != `hi\` || errors.As(err, &escapeAtEnd) == false {
+		t.Errorf("expected [hi\\] with an escape diagnostic, found [%s] %v", between, err)
+	}
+
+	pos := Position{Offset: 100, Line: 10, Column: 1, ByteColumn: 1}
+	_, _, err = BetweenWith([]byte("["), []byte("]"), nil, []byte("  [a"), BetweenOptions{Pos: pos})
+	if errors.As(err, &unterminated) == false || unterminated.OpenPos.Line != 10 || unterminated.OpenPos.Offset != 102 {
+		t.Errorf("expected [ opened at offset 102 on line 10, found %v", err)
+	}
+}
+
+func TestBetweenMultiByte(t *testing.T) {
+	between, rest, err := Between([]byte("{{"), []byte("}}"), nil, []byte("Hello {{ name }}!"))
+	if err != nil || string(between) != " name " || string(rest) != "!" {
+		t.Errorf("expected [ name ] and [!], found [%s] [%s] %v", between, rest, err)
+	}
+
+	between, _, err = Between([]byte("<!--"), []byte("-->"), nil, []byte("<p><!-- a -- b --></p>"))
+	if err != nil || string(between) != " a -- b " {
+		t.Errorf("expected [ a -- b ], found [%s] %v", between, err)
+	}
+
+	between, rest, err = Between([]byte(`"""`), []byte(`"""`), []byte(`\`), []byte(`doc = """a "quoted" \""" word""" + x`))
+	if err != nil || string(between) != `a "quoted" \""" word` || string(rest) != " + x" {
+		t.Errorf("expected [a \"quoted\" \\\"\"\" word], found [%s] [%s] %v", between, rest, err)
+	}
+
+	// Nested comments are honoured by default, Flat ends at the first close
+	src := []byte("/* a /* b */ c */ d")
+	between, _, err = Between([]byte("/*"), []byte("*/"), nil, src)
+	if err != nil || string(between) != " a /* b */ c " {
+		t.Errorf("expected nested [ a /* b */ c ], found [%s] %v", between, err)
+	}
+	between, rest, err = BetweenWith([]byte("/*"), []byte("*/"), nil, src, BetweenOptions{Flat: true})
+	if err != nil || string(between) != " a /* b " || string(rest) != " c */ d" {
+		t.Errorf("expected flat [ a /* b ], found [%s] [%s] %v", between, rest, err)
+	}
+
+	between, _, err = BetweenWith([]byte("("), []byte(")"), nil, []byte("f(a, (b)) + 1"), BetweenOptions{Inclusive: true})
+	if err != nil || string(between) != "(a, (b))" {
+		t.Errorf("expected inclusive [(a, (b))], found [%s] %v", between, err)
+	}
+
+	// Quotes suspend delimiter matching
+	src = []byte(`{ name: "}{", other: '\'}' } tail`)
+	between, rest, err = BetweenWith([]byte("{"), []byte("}"), []byte(`\`), src, BetweenOptions{Quotes: [][]byte{[]byte(`"`), []byte(`'`)}})
+	if err != nil || string(between) != ` name: "}{", other: '\'}' ` || string(rest) != " tail" {
+		t.Errorf("expected quotes to be skipped, found [%s] [%s] %v", between, rest, err)
+	}
+	_, _, err = BetweenWith([]byte("{"), []byte("}"), nil, []byte(`{ "} `), BetweenOptions{Quotes: [][]byte{[]byte(`"`)}})
+	var unterminated *UnterminatedError
+	if errors.As(err, &unterminated) == false {
+		t.Errorf("expected an unterminated region, found %v", err)
+	}
+	quotes := BetweenOptions{Quotes: [][]byte{[]byte(`"`)}}
+	var escapeAtEnd *EscapeAtEndError
+	_, _, err = BetweenWith([]byte("{"), []byte("}"), []byte(`\`), []byte(`{ "a\`), quotes)
+	if errors.As(err, &escapeAtEnd) == false {
+		t.Errorf("expected *EscapeAtEndError in a quote, found %v", err)
+	} else if escapeAtEnd.Pos.Column != 5 || escapeAtEnd.Depth != 1 {
+		t.Errorf("expected escape at 1:5 at depth 1, found %s at depth %d", escapeAtEnd.Pos, escapeAtEnd.Depth)
+	}
+	quotes.Recover = true
+	between, _, err = BetweenWith([]byte("{"), []byte("}"), []byte(`\`), []byte(`{ "a\`), quotes)
+	if errors.As(err, &escapeAtEnd) == false || errors.As(err, &unterminated) == false || string(between) != ` "a\` {
+		t.Errorf("expected recovered [ \"a\\] with both errors, found [%s] %v", between, err)
+	}
+
+	if _, _, err := Between(nil, []byte("}"), nil, []byte("}")); err == nil {
+		t.Errorf("expected an error for an empty delimiter")
+	}
+}
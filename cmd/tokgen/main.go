@@ -0,0 +1,421 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+// tokgen reads a lexer spec and writes a Go file holding a table driven lexer
+// that returns *tok.Token values. It is intended to be run by go generate, e.g.
+//
+//	//go:generate tokgen -o calc_lexer.go calc.tok
+//
+// A spec is line oriented, blank lines and lines starting with "#" are ignored.
+//
+//	package calc               the package of the generated file (required)
+//	lexer Lexer                the name of the generated type (default Lexer)
+//	mode main                  starts the rules for a mode, the first mode is the initial one
+//	Number [0-9]+              a token type followed by its pattern (RE2 syntax, no spaces, use \s or \x20)
+//	Space \s+ skip             tokens matching skip rules are not returned
+//	Quote " push string        push switches to a mode after the token, pop returns to the previous one
+//	keyword Ident If if        a token of type Ident with the value "if" is returned as type If
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/rsdoiel/tok"
+)
+
+// keyword retypes tokens of ruleType with value to keywordType
+type keyword struct {
+	ruleType    string
+	keywordType string
+	value       string
+}
+
+// spec is a parsed lexer spec
+type spec struct {
+	pkg      string
+	lexer    string
+	modes    []*tok.Mode
+	keywords []keyword
+}
+
+// parseSpec reads a lexer spec from r, name is used in error messages
+func parseSpec(name string, r io.Reader) (*spec, error) {
+	var (
+		mode *tok.Mode
+	)
+	s := &spec{lexer: "Lexer"}
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		errorf := func(format string, args ...interface{}) error {
+			return fmt.Errorf("%s:%d: %s", name, lineNo, fmt.Sprintf(format, args...))
+		}
+		switch fields[0] {
+		case "package", "lexer", "mode":
+			if len(fields) != 2 {
+				return nil, errorf("expected %s <name>", fields[0])
+			}
+			switch fields[0] {
+			case "package":
+				s.pkg = fields[1]
+			case "lexer":
+				s.lexer = fields[1]
+			case "mode":
+				if s.modeIndex(fields[1]) >= 0 {
+					return nil, errorf("mode %s is already defined", fields[1])
+				}
+				mode = &tok.Mode{Name: fields[1]}
+				s.modes = append(s.modes, mode)
+			}
+		case "keyword":
+			if len(fields) != 4 {
+				return nil, errorf("expected keyword <rule type> <keyword type> <value>")
+			}
+			s.keywords = append(s.keywords, keyword{ruleType: fields[1], keywordType: fields[2], value: fields[3]})
+		default:
+			if len(fields) < 2 {
+				return nil, errorf("expected <token type> <pattern> [skip|push <mode>|pop]")
+			}
+			if mode == nil {
+				mode = &tok.Mode{Name: "main"}
+				s.modes = append(s.modes, mode)
+			}
+			rule := tok.Rule{Type: fields[0], Pattern: fields[1]}
+			for actions := fields[2:]; len(actions) > 0; actions = actions[1:] {
+				switch actions[0] {
+				case "skip":
+					rule.Skip = true
+				case "pop":
+					rule.Pop = true
+				case "push":
+					if len(actions) < 2 {
+						return nil, errorf("push requires a mode")
+					}
+					rule.Push, actions = actions[1], actions[1:]
+				default:
+					return nil, errorf("unknown action %q", actions[0])
+				}
+			}
+			mode.Rules = append(mode.Rules, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if s.pkg == "" {
+		return nil, fmt.Errorf("%s: missing package", name)
+	}
+	if len(s.modes) == 0 {
+		return nil, fmt.Errorf("%s: no rules", name)
+	}
+	for _, m := range s.modes {
+		for _, rule := range m.Rules {
+			if rule.Push != "" && s.modeIndex(rule.Push) < 0 {
+				return nil, fmt.Errorf("%s: rule %s pushes unknown mode %s", name, rule.Type, rule.Push)
+			}
+		}
+	}
+	return s, nil
+}
+
+// modeIndex returns the index of the named mode or -1
+func (s *spec) modeIndex(name string) int {
+	for i, m := range s.modes {
+		if m.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// modeTables is the data handed to the template for each mode
+type modeTables struct {
+	Name       string
+	Bounds     string
+	Classes    string
+	NumClasses int
+	Trans      string
+	Accept     string
+	Start      int
+	Types      string
+	Skip       string
+	Push       string
+	Pop        string
+}
+
+// ints formats a list of numbers as the body of a Go slice literal
+func ints(values []int) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = fmt.Sprintf("%d", v)
+	}
+	return strings.Join(s, ", ")
+}
+
+// generate writes the Go source of the lexer described by s
+func generate(source string, s *spec) ([]byte, error) {
+	var modes []modeTables
+	for _, m := range s.modes {
+		d, err := tok.CompileDFA(m.Rules...)
+		if err != nil {
+			return nil, fmt.Errorf("mode %s, %s", m.Name, err)
+		}
+		bounds := make([]int, len(d.Bounds))
+		for i, r := range d.Bounds {
+			bounds[i] = int(r)
+		}
+		var types, skip, push, pop []string
+		for _, rule := range m.Rules {
+			types = append(types, fmt.Sprintf("%q", rule.Type))
+			skip = append(skip, fmt.Sprintf("%t", rule.Skip))
+			push = append(push, fmt.Sprintf("%d", s.modeIndex(rule.Push)))
+			pop = append(pop, fmt.Sprintf("%t", rule.Pop))
+		}
+		modes = append(modes, modeTables{
+			Name:       m.Name,
+			Bounds:     ints(bounds),
+			Classes:    ints(d.Classes),
+			NumClasses: d.NumClasses,
+			Trans:      ints(d.Trans),
+			Accept:     ints(d.Accept),
+			Start:      d.Start,
+			Types:      strings.Join(types, ", "),
+			Skip:       strings.Join(skip, ", "),
+			Push:       strings.Join(push, ", "),
+			Pop:        strings.Join(pop, ", "),
+		})
+	}
+	keywords := map[string]map[string]string{}
+	for _, k := range s.keywords {
+		if keywords[k.ruleType] == nil {
+			keywords[k.ruleType] = map[string]string{}
+		}
+		keywords[k.ruleType][k.value] = k.keywordType
+	}
+	prefix := strings.ToLower(s.lexer[0:1]) + s.lexer[1:]
+	out := &bytes.Buffer{}
+	err := lexerTemplate.Execute(out, map[string]interface{}{
+		"Source":   filepath.Base(source),
+		"Package":  s.pkg,
+		"Lexer":    s.lexer,
+		"Prefix":   prefix,
+		"Modes":    modes,
+		"Keywords": keywords,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(out.Bytes())
+}
+
+var lexerTemplate = template.Must(template.New("lexer").Parse(`// Code generated by tokgen from {{.Source}}; DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"github.com/rsdoiel/tok"
+)
+
+// {{.Prefix}}Mode holds the DFA tables and rule actions of a mode
+type {{.Prefix}}Mode struct {
+	name       string
+	bounds     []rune
+	classes    []int
+	numClasses int
+	trans      []int
+	accept     []int
+	start      int
+	types      []string
+	skip       []bool
+	push       []int
+	pop        []bool
+}
+
+var {{.Prefix}}Modes = []{{.Prefix}}Mode{
+{{- range .Modes}}
+	{
+		name:       {{printf "%q" .Name}},
+		bounds:     []rune{ {{- .Bounds -}} },
+		classes:    []int{ {{- .Classes -}} },
+		numClasses: {{.NumClasses}},
+		trans:      []int{ {{- .Trans -}} },
+		accept:     []int{ {{- .Accept -}} },
+		start:      {{.Start}},
+		types:      []string{ {{- .Types -}} },
+		skip:       []bool{ {{- .Skip -}} },
+		push:       []int{ {{- .Push -}} },
+		pop:        []bool{ {{- .Pop -}} },
+	},
+{{- end}}
+}
+
+var {{.Prefix}}Keywords = map[string]map[string]string{
+{{- range $type, $words := .Keywords}}
+	{{printf "%q" $type}}: {
+	{{- range $value, $keyword := $words}}
+		{{printf "%q" $value}}: {{printf "%q" $keyword}},
+	{{- end}}
+	},
+{{- end}}
+}
+
+// {{.Lexer}} returns the tokens of a buffer by longest match, the earlier rule in a mode wins ties.
+// Input no rule matches is returned one code point at a time as by tok.Tok().
+type {{.Lexer}} struct {
+	buf   []byte
+	pos   tok.Position
+	modes []int
+}
+
+// New{{.Lexer}} returns a {{.Lexer}} for buf starting in the first mode
+func New{{.Lexer}}(buf []byte) *{{.Lexer}} {
+	return &{{.Lexer}}{
+		buf:   buf,
+		pos:   tok.StartPosition(),
+		modes: []int{0},
+	}
+}
+
+// Mode returns the name of the current mode
+func (l *{{.Lexer}}) Mode() string {
+	return {{.Prefix}}Modes[l.modes[len(l.modes)-1]].name
+}
+
+// Next returns the next token, an EOF token is returned at the end of the buffer
+func (l *{{.Lexer}}) Next() *tok.Token {
+	for {
+		m := &{{.Prefix}}Modes[l.modes[len(l.modes)-1]]
+		rule, length := l.match(m)
+		if rule < 0 {
+			token, rest := tok.TokAt(l.buf, l.pos)
+			l.buf, l.pos = rest, token.End()
+			return token
+		}
+		value := l.buf[0:length]
+		pos := l.pos
+		l.buf, l.pos = l.buf[length:], l.pos.Advance(value)
+		if m.pop[rule] == true && len(l.modes) > 1 {
+			l.modes = l.modes[:len(l.modes)-1]
+		}
+		if m.push[rule] >= 0 {
+			l.modes = append(l.modes, m.push[rule])
+		}
+		if m.skip[rule] == true {
+			continue
+		}
+		tokenType := m.types[rule]
+		if keyword, ok := {{.Prefix}}Keywords[tokenType][string(value)]; ok == true {
+			tokenType = keyword
+		}
+		return &tok.Token{
+			Type:  tokenType,
+			Value: value,
+			Pos:   pos,
+		}
+	}
+}
+
+// match returns the rule matching the longest prefix of the buffer in mode m and its length
+func (l *{{.Lexer}}) match(m *{{.Prefix}}Mode) (int, int) {
+	var (
+		r    rune
+		size int
+	)
+	rule, length := -1, 0
+	state := m.start
+	for i := 0; i < len(l.buf); i += size {
+		r, size = utf8.DecodeRune(l.buf[i:])
+		c := m.classes[sort.Search(len(m.bounds), func(j int) bool { return m.bounds[j] > r })-1]
+		state = m.trans[state*m.numClasses+c]
+		if state < 0 {
+			break
+		}
+		if m.accept[state] >= 0 {
+			rule, length = m.accept[state], i+size
+		}
+	}
+	return rule, length
+}
+`))
+
+func main() {
+	var output string
+	flag.StringVar(&output, "o", "", "the Go file to write (default is the spec file name with a .go extension)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "USAGE: %s [-o OUTPUT] SPEC_FILE\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	fname := flag.Arg(0)
+	if output == "" {
+		output = strings.TrimSuffix(fname, filepath.Ext(fname)) + ".go"
+	}
+	fp, err := os.Open(fname)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+	s, err := parseSpec(fname, fp)
+	fp.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+	src, err := generate(fname, s)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s, %s\n", fname, err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(output, src, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		os.Exit(1)
+	}
+}
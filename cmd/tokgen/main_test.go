@@ -0,0 +1,233 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const calcSpec = `# a small calculator with strings
+package calc
+lexer CalcLexer
+
+mode main
+Number  [0-9]+
+Ident   [A-Za-z_]\w*
+Op      [-+*/=]
+Space   \s+      skip
+Quote   "        push string
+
+mode string
+Text    [^"\\]+
+Escape  \\.
+Quote   "        pop
+
+keyword Ident If if
+`
+
+func TestParseSpec(t *testing.T) {
+	s, err := parseSpec("calc.tok", strings.NewReader(calcSpec))
+	if err != nil {
+		t.Errorf("parseSpec() failed, %s", err)
+		t.FailNow()
+	}
+	if s.pkg != "calc" || s.lexer != "CalcLexer" {
+		t.Errorf("expected package calc and lexer CalcLexer, found %s and %s", s.pkg, s.lexer)
+	}
+	if len(s.modes) != 2 || s.modes[0].Name != "main" || len(s.modes[0].Rules) != 5 || len(s.modes[1].Rules) != 3 {
+		t.Errorf("expected modes main (5 rules) and string (3 rules), found %+v", s.modes)
+		t.FailNow()
+	}
+	if rule := s.modes[0].Rules[4]; rule.Type != "Quote" || rule.Push != "string" {
+		t.Errorf("expected Quote to push string, found %+v", rule)
+	}
+	if rule := s.modes[1].Rules[2]; rule.Pop == false {
+		t.Errorf("expected Quote to pop, found %+v", rule)
+	}
+	if rule := s.modes[0].Rules[3]; rule.Skip == false {
+		t.Errorf("expected Space to be skipped, found %+v", rule)
+	}
+	if len(s.keywords) != 1 || s.keywords[0].keywordType != "If" {
+		t.Errorf("expected keyword If, found %+v", s.keywords)
+	}
+
+	for _, bad := range []string{
+		"mode main\nNumber [0-9]+\n",
+		"package calc\nQuote \" push nowhere\n",
+		"package calc\nNumber [0-9]+ twice\n",
+		"package calc\n",
+	} {
+		if _, err := parseSpec("bad.tok", strings.NewReader(bad)); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	s, err := parseSpec("calc.tok", strings.NewReader(calcSpec))
+	if err != nil {
+		t.Errorf("parseSpec() failed, %s", err)
+		t.FailNow()
+	}
+	src, err := generate("calc.tok", s)
+	if err != nil {
+		t.Errorf("generate() failed, %s", err)
+		t.FailNow()
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), "calc_lexer.go", src, 0)
+	if err != nil {
+		t.Errorf("generated source does not parse, %s\n%s", err, src)
+		t.FailNow()
+	}
+	if f.Name.Name != "calc" {
+		t.Errorf("expected package calc, found %s", f.Name.Name)
+	}
+	for _, expected := range []string{"type CalcLexer struct", "func NewCalcLexer(buf []byte) *CalcLexer", `"If"`, "calcLexerModes"} {
+		if strings.Contains(string(src), expected) == false {
+			t.Errorf("expected generated source to contain %q", expected)
+		}
+	}
+
+	s.modes[0].Rules[0].Pattern = `^[0-9]+`
+	if _, err := generate("calc.tok", s); err == nil {
+		t.Errorf("expected an error for an anchored pattern")
+	}
+}
+
+// generatedMain prints the tokens the generated CalcLexer returns for standard input
+const generatedMain = `package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rsdoiel/tok"
+)
+
+func main() {
+	src, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	l := NewCalcLexer(src)
+	for {
+		t := l.Next()
+		if t.Type == tok.EOF {
+			break
+		}
+		fmt.Printf("%s %q %s %s\n", t.Type, t.Value, t.Pos, l.Mode())
+	}
+}
+`
+
+func TestGeneratedLexer(t *testing.T) {
+	goCmd, err := exec.LookPath("go")
+	if err != nil || testing.Short() == true {
+		t.Skip("needs the go command to build the generated lexer")
+	}
+	s, err := parseSpec("calc.tok", strings.NewReader(calcSpec))
+	if err != nil {
+		t.Errorf("parseSpec() failed, %s", err)
+		t.FailNow()
+	}
+	s.pkg = "main"
+	src, err := generate("calc.tok", s)
+	if err != nil {
+		t.Errorf("generate() failed, %s", err)
+		t.FailNow()
+	}
+	// Build in its own module using this checkout of tok
+	root, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"go.mod":        []byte("module generated\n\ngo 1.23\n\nrequire github.com/rsdoiel/tok v0.0.0\n\nreplace github.com/rsdoiel/tok => " + root + "\n"),
+		"calc_lexer.go": src,
+		"main.go":       []byte(generatedMain),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env := append(os.Environ(), "GO111MODULE=on", "GOFLAGS=-mod=mod")
+
+	vet := exec.Command(goCmd, "vet", ".")
+	vet.Dir, vet.Env = dir, env
+	if out, err := vet.CombinedOutput(); err != nil {
+		t.Errorf("go vet of the generated lexer failed, %s\n%s\n%s", err, out, src)
+		t.FailNow()
+	}
+	run := exec.Command(goCmd, "run", ".")
+	run.Dir, run.Env = dir, env
+	run.Stdin = strings.NewReader("if x = 42 + \"a\\\"b\" @y\n")
+	out, err := run.Output()
+	if err != nil {
+		t.Errorf("running the generated lexer failed, %s", err)
+		t.FailNow()
+	}
+	expected := []string{
+		`If "if" 1:1 main`,
+		`Ident "x" 1:4 main`,
+		`Op "=" 1:6 main`,
+		`Number "42" 1:8 main`,
+		`Op "+" 1:11 main`,
+		`Quote "\"" 1:13 string`,
+		`Text "a" 1:14 string`,
+		`Escape "\\\"" 1:15 string`,
+		`Text "b" 1:17 string`,
+		`Quote "\"" 1:18 main`,
+		`Punctuation "@" 1:20 main`,
+		`Ident "y" 1:21 main`,
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != len(expected) {
+		t.Errorf("expected %d tokens, found %d\n%s", len(expected), len(lines), out)
+		t.FailNow()
+	}
+	for i, line := range lines {
+		if line != expected[i] {
+			t.Errorf("(%d) expected %s, found %s", i, expected[i], line)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"bytes"
+)
+
+const (
+	// Comment is a line or block comment (e.g. // note, /* note */)
+	Comment = "Comment"
+	// DocComment is a documentation comment (e.g. /// note, /** note */)
+	DocComment = "DocComment"
+)
+
+// CommentBlock describes a block comment's delimiters
+type CommentBlock struct {
+	// Open and Close delimit the comment (e.g. "/*" and "*/")
+	Open  []byte
+	Close []byte
+	// Nested comments close only when every Open inside them is closed (e.g. Haskell's {- -})
+	Nested bool
+}
+
+// CommentStyle describes the comments of a format, its Tokenizer method recognizes them
+type CommentStyle struct {
+	// Line are the markers starting a comment running to the end of the line (e.g. "//", "#")
+	Line [][]byte
+	// Blocks are the block comment delimiters (e.g. "/*" and "*/")
+	Blocks []CommentBlock
+	// Doc are prefixes of comments returned as DocComment (e.g. "///", "/**"), a prefix followed
+	// by its own last byte is an ordinary comment (e.g. "////")
+	Doc [][]byte
+	// Drop makes the Tokenizer consume comments and return the token following them
+	Drop bool
+}
+
+var (
+	// CComments are C, Go, Java and JavaScript comments
+	CComments = CommentStyle{
+		Line:   [][]byte{[]byte("//")},
+		Blocks: []CommentBlock{{Open: []byte("/*"), Close: []byte("*/")}},
+		Doc:    [][]byte{[]byte("///"), []byte("/**")},
+	}
+	// ShellComments are shell, Python, Ruby and YAML comments
+	ShellComments = CommentStyle{
+		Line: [][]byte{[]byte("#")},
+	}
+	// SQLComments are SQL comments
+	SQLComments = CommentStyle{
+		Line:   [][]byte{[]byte("--")},
+		Blocks: []CommentBlock{{Open: []byte("/*"), Close: []byte("*/")}},
+	}
+	// LispComments are Lisp, Scheme and INI file comments
+	LispComments = CommentStyle{
+		Line: [][]byte{[]byte(";")},
+	}
+	// MLComments are OCaml, Standard ML and Pascal style nested (* *) comments
+	MLComments = CommentStyle{
+		Blocks: []CommentBlock{{Open: []byte("(*"), Close: []byte("*)"), Nested: true}},
+		Doc:    [][]byte{[]byte("(**")},
+	}
+	// HaskellComments are Haskell comments including nested {- -} blocks
+	HaskellComments = CommentStyle{
+		Line:   [][]byte{[]byte("--")},
+		Blocks: []CommentBlock{{Open: []byte("{-"), Close: []byte("-}"), Nested: true}},
+		Doc:    [][]byte{[]byte("-- |"), []byte("{-|")},
+	}
+)
+
+// hasSplitPrefix reports if value followed by buf starts with prefix, a value longer
+// than prefix (e.g. "REMARK" for "REM") was already claimed by an earlier stage and isn't a match
+func hasSplitPrefix(value []byte, buf []byte, prefix []byte) bool {
+	if len(value) > len(prefix) {
+		return false
+	}
+	return bytes.HasPrefix(prefix, value) && bytes.HasPrefix(buf, prefix[len(value):])
+}
+
+// match returns the type of a comment starting with value followed by buf and how many
+// bytes of buf it takes, ok is false if there isn't a comment
+func (c CommentStyle) match(value []byte, buf []byte) (string, int, bool) {
+	var (
+		line  []byte
+		block *CommentBlock
+	)
+	// The longest opening marker wins (e.g. Lua's "--[[" over "--")
+	for _, marker := range c.Line {
+		if len(marker) > len(line) && hasSplitPrefix(value, buf, marker) {
+			line = marker
+		}
+	}
+	for i := range c.Blocks {
+		open := c.Blocks[i].Open
+		if len(open) > len(line) && (block == nil || len(open) > len(block.Open)) && hasSplitPrefix(value, buf, open) {
+			block = &c.Blocks[i]
+		}
+	}
+	var n, inner int
+	switch {
+	case block != nil:
+		depth := 1
+		n = len(block.Open) - len(value)
+		for depth > 0 {
+			if n >= len(buf) {
+				return Invalid, len(buf), true
+			}
+			switch {
+			case bytes.HasPrefix(buf[n:], block.Close):
+				n += len(block.Close)
+				depth--
+			case block.Nested == true && bytes.HasPrefix(buf[n:], block.Open):
+				n += len(block.Open)
+				depth++
+			default:
+				n++
+			}
+		}
+		inner = n - len(block.Close)
+	case line != nil:
+		n = len(line) - len(value)
+		if i := bytes.IndexByte(buf[n:], '\n'); i >= 0 {
+			n += i
+		} else {
+			n = len(buf)
+		}
+		inner = n
+	default:
+		return "", 0, false
+	}
+	for _, prefix := range c.Doc {
+		// Compare the comment without its closing delimiter so "/**/" isn't a doc comment
+		if hasSplitPrefix(value, buf[0:inner], prefix) {
+			next := len(prefix) - len(value)
+			if next >= inner || buf[next] != prefix[len(prefix)-1] {
+				return DocComment, n, true
+			}
+		}
+	}
+	return Comment, n, true
+}
+
+// Tokenizer returns a Comment or DocComment token for a comment starting at tok, an unterminated
+// block comment is returned as an Invalid token. With Drop set comments are consumed and the
+// following token is returned. It can be passed to Tok2().
+func (c CommentStyle) Tokenizer(tok *Token, buf []byte) (*Token, []byte) {
+	for {
+		tokenType, n, ok := c.match(tok.Value, buf)
+		if ok == false {
+			return tok, buf
+		}
+		token := &Token{
+			Type:  tokenType,
+			Value: join(tok.Value, buf[0:n]),
+			Pos:   tok.Pos,
+		}
+		buf = buf[n:]
+		if c.Drop == false || tokenType == Invalid {
+			return token, buf
+		}
+		tok, buf = TokAt(buf, token.End())
+	}
+}
@@ -0,0 +1,139 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"testing"
+)
+
+func TestComments(t *testing.T) {
+	for _, test := range []struct {
+		Name  string
+		Style CommentStyle
+		Src   string
+		Type  string
+		Value string
+		Rest  string
+	}{
+		{"line", CComments, "// note\nx", Comment, "// note", "\nx"},
+		{"line at end", CComments, "// note", Comment, "// note", ""},
+		{"doc line", CComments, "/// note\nx", DocComment, "/// note", "\nx"},
+		{"not doc", CComments, "//// rule\nx", Comment, "//// rule", "\nx"},
+		{"block", CComments, "/* a\nb */x", Comment, "/* a\nb */", "x"},
+		{"doc block", CComments, "/** a */x", DocComment, "/** a */", "x"},
+		{"empty block", CComments, "/**/x", Comment, "/**/", "x"},
+		{"flat block", CComments, "/* a /* b */ c */", Comment, "/* a /* b */", " c */"},
+		{"unterminated", CComments, "/* a", Invalid, "/* a", ""},
+		{"division", CComments, "/ 2", Punctuation, "/", " 2"},
+		{"shell", ShellComments, "# note\nx", Comment, "# note", "\nx"},
+		{"sql", SQLComments, "-- note\nx", Comment, "-- note", "\nx"},
+		{"minus", SQLComments, "-1", Punctuation, "-", "1"},
+		{"lisp", LispComments, "; note\nx", Comment, "; note", "\nx"},
+		{"ml nested", MLComments, "(* a (* b *) c *) x", Comment, "(* a (* b *) c *)", " x"},
+		{"ml doc", MLComments, "(** a *) x", DocComment, "(** a *)", " x"},
+		{"haskell nested", HaskellComments, "{- a {- b -} c -} x", Comment, "{- a {- b -} c -}", " x"},
+		{"haskell doc", HaskellComments, "-- | note\nx", DocComment, "-- | note", "\nx"},
+		{"haskell unterminated", HaskellComments, "{- a {- b -}", Invalid, "{- a {- b -}", ""},
+	} {
+		token, buf := Tok2([]byte(test.Src), test.Style.Tokenizer)
+		if token.Type != test.Type || string(token.Value) != test.Value || string(buf) != test.Rest {
+			t.Errorf("%s: expected %s %q then %q, found %s then %q", test.Name, test.Type, test.Value, test.Rest, token, buf)
+		}
+	}
+
+	// A longer marker wins, e.g. Lua's block comment over its line comment
+	lua := CommentStyle{
+		Line:   [][]byte{[]byte("--")},
+		Blocks: []CommentBlock{{Open: []byte("--[["), Close: []byte("]]")}},
+	}
+	token, buf := Tok2([]byte("--[[ a\nb ]] x"), lua.Tokenizer)
+	if string(token.Value) != "--[[ a\nb ]]" || string(buf) != " x" {
+		t.Errorf("expected the block comment, found %s then %q", token, buf)
+	}
+}
+
+func TestCommentsDrop(t *testing.T) {
+	style := CComments
+	style.Drop = true
+	src := []byte("a /* one */// two\n/* three */b")
+	values := []string{}
+	for token := range AllWith(src, style.Tokenizer) {
+		values = append(values, string(token.Value))
+		if string(token.Value) == "b" && (token.Pos.Line != 2 || token.Pos.Column != 12) {
+			t.Errorf("expected b at 2:12, found %s", token.Pos)
+		}
+	}
+	expected := []string{"a", " ", "\n", "b"}
+	if len(values) != len(expected) {
+		t.Fatalf("expected %q, found %q", expected, values)
+	}
+	for i := range expected {
+		if values[i] != expected[i] {
+			t.Errorf("expected %q, found %q", expected, values)
+			break
+		}
+	}
+
+	// Kept comments can be dropped later as trivia
+	count := 0
+	for range Drop(AllWith(src, CComments.Tokenizer), Comment, DocComment, Space) {
+		count++
+	}
+	if count != 2 {
+		t.Errorf("expected 2 tokens without trivia, found %d", count)
+	}
+}
+
+func TestCommentsChained(t *testing.T) {
+	// A token longer than the marker comes from an earlier stage and isn't a comment
+	rem := CommentStyle{Line: [][]byte{[]byte("REM")}}
+	token, _ := Tok2([]byte("REMARK x\n"), Chain(Words, rem.Tokenizer))
+	if token.Type != Word || string(token.Value) != "REMARK" {
+		t.Errorf("expected Word REMARK, found %s %q", token.Type, token.Value)
+	}
+	token, _ = Tok2([]byte("REM x\n"), Chain(Words, rem.Tokenizer))
+	if token.Type != Comment || string(token.Value) != "REM x" {
+		t.Errorf("expected Comment \"REM x\", found %s %q", token.Type, token.Value)
+	}
+
+	ops := NewOperators()
+	ops.Add("Arrow", []byte("-->"))
+	token, _ = Tok2([]byte("--> y\n"), Chain(ops.Tokenizer, SQLComments.Tokenizer))
+	if token.Type != "Arrow" || string(token.Value) != "-->" {
+		t.Errorf("expected Arrow -->, found %s %q", token.Type, token.Value)
+	}
+	token, _ = Tok2([]byte("-- y\n"), Chain(ops.Tokenizer, SQLComments.Tokenizer))
+	if token.Type != Comment || string(token.Value) != "-- y" {
+		t.Errorf("expected Comment \"-- y\", found %s %q", token.Type, token.Value)
+	}
+}
@@ -0,0 +1,207 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+// Cursor walks the tokens of a buffer produced by Tok2() with a Tokenizer, keeping the
+// tokens it has looked ahead at in a ring buffer so they are only produced once. This
+// makes LL(k) parsing practical, see PeekN(). Mark() and Reset() support backtracking.
+// Once the end of the buffer is reached the EOF token is returned for every further token.
+type Cursor struct {
+	buf   []byte
+	pos   Position
+	fn    Tokenizer
+	modes *ModeLexer
+	eof   *Token
+	ring  []cursorEntry
+	head  int
+	size  int
+}
+
+// cursorEntry is a token in the ring along with the input and mode stack it was produced from
+type cursorEntry struct {
+	token *Token
+	buf   []byte
+	pos   Position
+	stack []string
+}
+
+// Checkpoint is a saved Cursor position returned by Mark()
+type Checkpoint struct {
+	buf   []byte
+	pos   Position
+	stack []string
+}
+
+// Pos returns the position of the token following the checkpoint
+func (cp Checkpoint) Pos() Position {
+	return cp.pos
+}
+
+// NewCursor returns a Cursor over buf using fn (e.g. Words), when fn is nil tokens are those of Tok()
+func NewCursor(buf []byte, fn Tokenizer) *Cursor {
+	if fn == nil {
+		fn = func(token *Token, buf []byte) (*Token, []byte) {
+			return token, buf
+		}
+	}
+	return &Cursor{
+		buf:  buf,
+		pos:  StartPosition(),
+		fn:   fn,
+		ring: make([]cursorEntry, 4),
+	}
+}
+
+// NewModeCursor returns a Cursor over buf using ml, the mode stack is saved by Mark() and restored by Reset()
+func NewModeCursor(buf []byte, ml *ModeLexer) *Cursor {
+	c := NewCursor(buf, ml.Tokenizer)
+	c.modes = ml
+	return c
+}
+
+// push adds an entry at the end of the ring, doubling it when full
+func (c *Cursor) push(entry cursorEntry) {
+	if c.size == len(c.ring) {
+		ring := make([]cursorEntry, len(c.ring)*2)
+		for i := 0; i < c.size; i++ {
+			ring[i] = c.ring[(c.head+i)%len(c.ring)]
+		}
+		c.ring, c.head = ring, 0
+	}
+	c.ring[(c.head+c.size)%len(c.ring)] = entry
+	c.size++
+}
+
+// fill makes sure at least n tokens are in the ring
+func (c *Cursor) fill(n int) {
+	for c.size < n {
+		entry := cursorEntry{
+			buf: c.buf,
+			pos: c.pos,
+		}
+		if c.modes != nil {
+			entry.stack = c.modes.Stack()
+		}
+		if c.eof != nil {
+			entry.token = c.eof
+			c.push(entry)
+			continue
+		}
+		entry.token, c.buf = Tok2At(c.buf, c.fn, c.pos)
+		c.pos = entry.token.End()
+		if entry.token.Type == EOF {
+			c.eof = entry.token
+		}
+		c.push(entry)
+	}
+}
+
+// Next consumes and returns the next token
+func (c *Cursor) Next() *Token {
+	c.fill(1)
+	token := c.ring[c.head].token
+	c.ring[c.head] = cursorEntry{}
+	c.head = (c.head + 1) % len(c.ring)
+	c.size--
+	return token
+}
+
+// Peek returns the next token without consuming it, the same as PeekN(0)
+func (c *Cursor) Peek() *Token {
+	return c.PeekN(0)
+}
+
+// PeekN returns the token n places ahead without consuming anything, PeekN(0) is the next token
+func (c *Cursor) PeekN(n int) *Token {
+	if n < 0 {
+		n = 0
+	}
+	c.fill(n + 1)
+	return c.ring[(c.head+n)%len(c.ring)].token
+}
+
+// Lookahead returns the next n tokens without consuming them
+func (c *Cursor) Lookahead(n int) []*Token {
+	tokens := make([]*Token, n)
+	for i := range tokens {
+		tokens[i] = c.PeekN(i)
+	}
+	return tokens
+}
+
+// Buffered returns how many tokens have been produced but not consumed yet
+func (c *Cursor) Buffered() int {
+	return c.size
+}
+
+// Pos returns the position of the next token
+func (c *Cursor) Pos() Position {
+	if c.size > 0 {
+		return c.ring[c.head].pos
+	}
+	return c.pos
+}
+
+// Mark returns a Checkpoint of the Cursor before the next token, Reset() returns to it.
+// Checkpoints may be nested, any earlier checkpoint of the Cursor can be reset to.
+func (c *Cursor) Mark() Checkpoint {
+	if c.size > 0 {
+		entry := c.ring[c.head]
+		return Checkpoint{buf: entry.buf, pos: entry.pos, stack: entry.stack}
+	}
+	cp := Checkpoint{buf: c.buf, pos: c.pos}
+	if c.modes != nil {
+		cp.stack = c.modes.Stack()
+	}
+	return cp
+}
+
+// Reset returns the Cursor to cp, restoring its position and the mode of a ModeLexer.
+// Tokens looked ahead at are discarded and produced again as needed.
+func (c *Cursor) Reset(cp Checkpoint) {
+	for i := 0; i < c.size; i++ {
+		c.ring[(c.head+i)%len(c.ring)] = cursorEntry{}
+	}
+	c.head, c.size = 0, 0
+	c.buf, c.pos, c.eof = cp.buf, cp.pos, nil
+	if c.modes != nil && cp.stack != nil {
+		c.modes.SetStack(cp.stack)
+	}
+}
+
+// Peek2 is like Peek but produces the token with Tok2() and fn, buf is not consumed.
+// Note a Tokenizer that keeps state (e.g. ModeLexer.Tokenizer) is still updated.
+func Peek2(buf []byte, fn Tokenizer) *Token {
+	token, _ := Tok2(buf, fn)
+	return token
+}
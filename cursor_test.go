@@ -0,0 +1,174 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	c := NewCursor([]byte("let x = y"), Words)
+	expected := []struct {
+		Type  string
+		Value string
+	}{
+		{Word, "let"},
+		{Space, " "},
+		{Letter, "x"},
+		{Space, " "},
+		{Punctuation, "="},
+		{Space, " "},
+		{Letter, "y"},
+		{EOF, ""},
+		{EOF, ""},
+	}
+	// Look all the way ahead, growing the ring
+	for i := len(expected) - 1; i >= 0; i-- {
+		token := c.PeekN(i)
+		if token.Type != expected[i].Type || string(token.Value) != expected[i].Value {
+			t.Errorf("PeekN(%d) expected {%q: %q}, found %s", i, expected[i].Type, expected[i].Value, token)
+		}
+	}
+	if c.Buffered() != len(expected) {
+		t.Errorf("expected %d buffered tokens, found %d", len(expected), c.Buffered())
+	}
+	for i, exp := range expected {
+		peeked := c.Peek()
+		token := c.Next()
+		if token != peeked {
+			t.Errorf("%d: expected Peek() to return the token Next() does, %s != %s", i, peeked, token)
+		}
+		if token.Type != exp.Type || string(token.Value) != exp.Value {
+			t.Errorf("%d: expected {%q: %q}, found %s", i, exp.Type, exp.Value, token)
+		}
+	}
+
+	// Interleaved lookahead and consumption wraps around the ring
+	c = NewCursor([]byte("a b c d e f g h i j"), nil)
+	for i := 0; i < 10; i++ {
+		ahead := c.Lookahead(3)
+		if i == 9 {
+			if ahead[1].Type != EOF || ahead[2].Type != EOF {
+				t.Errorf("%d: expected EOF lookahead %v", i, ahead)
+			}
+		} else if ahead[0].Type != Letter || ahead[1].Type != Space || string(ahead[2].Value) != string(rune('a'+i+1)) {
+			t.Errorf("%d: unexpected lookahead %v", i, ahead)
+		}
+		if token := c.Next(); string(token.Value) != string(rune('a'+i)) {
+			t.Errorf("%d: expected %c, found %s", i, 'a'+i, token)
+		}
+		c.Next()
+	}
+	if c.Next().Type != EOF {
+		t.Errorf("expected EOF")
+	}
+
+	if token := Peek2([]byte("word up"), Words); token.Type != Word || string(token.Value) != "word" {
+		t.Errorf("expected {Word: word}, found %s", token)
+	}
+}
+
+func TestCursorCheckpoint(t *testing.T) {
+	c := NewCursor([]byte("one two\nthree four"), Words)
+	c.Next()
+	outer := c.Mark()
+	c.Next()
+	if token := c.Next(); string(token.Value) != "two" {
+		t.Errorf("expected two, found %s", token)
+	}
+	inner := c.Mark()
+	c.PeekN(3)
+	c.Next()
+	if token := c.Next(); string(token.Value) != "three" || token.Pos.Line != 2 {
+		t.Errorf("expected three on line 2, found %s at %s", token, token.Pos)
+	}
+	c.Reset(inner)
+	if token := c.Next(); token.Type != Space || token.Pos.Column != 8 {
+		t.Errorf("expected the newline at 1:8 after Reset(inner), found %s at %s", token, token.Pos)
+	}
+	c.Reset(outer)
+	if c.Pos() != outer.Pos() || outer.Pos().Column != 4 {
+		t.Errorf("expected to be back at 1:4, found %s", c.Pos())
+	}
+	c.Next()
+	if token := c.Next(); string(token.Value) != "two" || token.Pos.Column != 5 {
+		t.Errorf("expected two at 1:5 after Reset(outer), found %s at %s", token, token.Pos)
+	}
+
+	// Resetting past the end of input clears EOF
+	for c.Next().Type != EOF {
+	}
+	c.Reset(outer)
+	if token := c.PeekN(1); string(token.Value) != "two" {
+		t.Errorf("expected two after resetting from EOF, found %s", token)
+	}
+}
+
+func TestModeCursorCheckpoint(t *testing.T) {
+	ml, err := NewModeLexer(
+		Mode{Name: "text", Rules: []Rule{
+			{Type: "Text", Pattern: `[^<]+`},
+			{Type: "Open", Pattern: `<`, Push: "tag"},
+		}},
+		Mode{Name: "tag", Rules: []Rule{
+			{Type: "Name", Pattern: `[a-z]+`},
+			{Type: "Close", Pattern: `>`, Pop: true},
+			{Type: Space, Pattern: `\s+`, Skip: true},
+		}},
+	)
+	if err != nil {
+		t.Errorf("NewModeLexer() failed, %s", err)
+		t.FailNow()
+	}
+	c := NewModeCursor([]byte("hi <b>there"), ml)
+	c.Next()
+	cp := c.Mark()
+	// Looking ahead moves the ModeLexer through the tag and back out
+	if token := c.PeekN(3); token.Type != "Text" || ml.Mode() != "text" {
+		t.Errorf("expected Text in mode text, found %s in %s", token, ml.Mode())
+	}
+	c.Next()
+	c.Next()
+	mid := c.Mark()
+	c.Reset(cp)
+	if ml.Mode() != "text" {
+		t.Errorf("expected mode text after Reset(), found %s", ml.Mode())
+	}
+	c.Reset(mid)
+	if ml.Mode() != "tag" {
+		t.Errorf("expected mode tag after Reset(mid), found %s", ml.Mode())
+	}
+	if token := c.Next(); token.Type != "Close" {
+		t.Errorf("expected Close, found %s", token)
+	}
+}
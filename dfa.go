@@ -0,0 +1,479 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"encoding/binary"
+	"fmt"
+	"regexp/syntax"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DFA is a set of Rules compiled into a single minimized deterministic finite automaton.
+// Tokenizing walks the table once per code point with no regular expression evaluation,
+// returning the longest match (the earlier rule wins ties) as RuleLexer does.
+//
+// The tables are exported so they can be written out as Go source (see cmd/tokgen).
+// A code point r belongs to the input class Classes[i] where Bounds[i] is the largest
+// bound <= r. Trans[state*NumClasses+class] is the next state or -1, Accept[state] is
+// the index of the rule accepted in state or -1.
+type DFA struct {
+	Rules      []Rule
+	Bounds     []rune
+	Classes    []int
+	NumClasses int
+	Trans      []int
+	Accept     []int
+	Start      int
+
+	// ascii caches the class of each ASCII code point
+	ascii [utf8.RuneSelf]int
+}
+
+// nfaState is a state of the Thompson NFA built from the rules, it moves on any code point
+// in ranges (lo, hi pairs) to next and on nothing to each of eps
+type nfaState struct {
+	eps    []int
+	ranges []rune
+	next   int
+	accept int
+}
+
+// nfa is built by compile and then converted into a DFA
+type nfa struct {
+	states []nfaState
+}
+
+func (n *nfa) add() int {
+	n.states = append(n.states, nfaState{next: -1, accept: -1})
+	return len(n.states) - 1
+}
+
+func (n *nfa) epsilon(from, to int) {
+	n.states[from].eps = append(n.states[from].eps, to)
+}
+
+func (n *nfa) move(from int, ranges []rune) int {
+	to := n.add()
+	n.states[from].ranges = ranges
+	n.states[from].next = to
+	return to
+}
+
+// literalRanges returns the ranges matching r, including its case folds when fold is true
+func literalRanges(r rune, fold bool) []rune {
+	ranges := []rune{r, r}
+	if fold == true {
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			ranges = append(ranges, f, f)
+		}
+	}
+	return ranges
+}
+
+// compile adds the states for re returning the start and end states of the fragment
+func (n *nfa) compile(re *syntax.Regexp) (int, int, error) {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return n.add(), n.add(), nil
+	case syntax.OpEmptyMatch:
+		s := n.add()
+		return s, s, nil
+	case syntax.OpLiteral:
+		s := n.add()
+		e := s
+		for _, r := range re.Rune {
+			e = n.move(e, literalRanges(r, re.Flags&syntax.FoldCase != 0))
+		}
+		return s, e, nil
+	case syntax.OpCharClass:
+		s := n.add()
+		return s, n.move(s, append([]rune{}, re.Rune...)), nil
+	case syntax.OpAnyCharNotNL:
+		s := n.add()
+		return s, n.move(s, []rune{0, '\n' - 1, '\n' + 1, unicode.MaxRune}), nil
+	case syntax.OpAnyChar:
+		s := n.add()
+		return s, n.move(s, []rune{0, unicode.MaxRune}), nil
+	case syntax.OpCapture:
+		return n.compile(re.Sub[0])
+	case syntax.OpConcat:
+		s := n.add()
+		e := s
+		for _, sub := range re.Sub {
+			subStart, subEnd, err := n.compile(sub)
+			if err != nil {
+				return 0, 0, err
+			}
+			n.epsilon(e, subStart)
+			e = subEnd
+		}
+		return s, e, nil
+	case syntax.OpAlternate:
+		s, e := n.add(), n.add()
+		for _, sub := range re.Sub {
+			subStart, subEnd, err := n.compile(sub)
+			if err != nil {
+				return 0, 0, err
+			}
+			n.epsilon(s, subStart)
+			n.epsilon(subEnd, e)
+		}
+		return s, e, nil
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest:
+		subStart, subEnd, err := n.compile(re.Sub[0])
+		if err != nil {
+			return 0, 0, err
+		}
+		s, e := n.add(), n.add()
+		n.epsilon(s, subStart)
+		n.epsilon(subEnd, e)
+		if re.Op != syntax.OpPlus {
+			n.epsilon(s, e)
+		}
+		if re.Op != syntax.OpQuest {
+			n.epsilon(subEnd, subStart)
+		}
+		return s, e, nil
+	}
+	return 0, 0, fmt.Errorf("%s is not supported", re)
+}
+
+// closure adds the states reachable from set without consuming input, returning them sorted
+func (n *nfa) closure(set []int) []int {
+	seen := map[int]bool{}
+	stack := append([]int{}, set...)
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[s] == true {
+			continue
+		}
+		seen[s] = true
+		stack = append(stack, n.states[s].eps...)
+	}
+	closed := make([]int, 0, len(seen))
+	for s := range seen {
+		closed = append(closed, s)
+	}
+	sort.Ints(closed)
+	return closed
+}
+
+// setKey returns a map key for a sorted set of states
+func setKey(set []int) string {
+	b := make([]byte, 4*len(set))
+	for i, s := range set {
+		binary.LittleEndian.PutUint32(b[4*i:], uint32(s))
+	}
+	return string(b)
+}
+
+// CompileDFA compiles rules into a minimized DFA. Patterns use RE2 syntax but anchors
+// and word boundaries are not supported since tokens are always matched at the current position.
+func CompileDFA(rules ...Rule) (*DFA, error) {
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no rules")
+	}
+	n := &nfa{}
+	start := n.add()
+	for i, rule := range rules {
+		if _, err := checkRule(i, rule); err != nil {
+			return nil, err
+		}
+		re, err := syntax.Parse(rule.Pattern, syntax.Perl)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d (%s), %s", i, rule.Type, err)
+		}
+		s, e, err := n.compile(re.Simplify())
+		if err != nil {
+			return nil, fmt.Errorf("rule %d (%s), %s", i, rule.Type, err)
+		}
+		n.epsilon(start, s)
+		n.states[e].accept = i
+	}
+
+	// Split the code points into intervals no NFA transition crosses
+	points := map[rune]bool{0: true}
+	for _, state := range n.states {
+		for i := 0; i < len(state.ranges); i += 2 {
+			points[state.ranges[i]] = true
+			if state.ranges[i+1] < unicode.MaxRune {
+				points[state.ranges[i+1]+1] = true
+			}
+		}
+	}
+	bounds := make([]rune, 0, len(points))
+	for r := range points {
+		bounds = append(bounds, r)
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+	interval := func(r rune) int {
+		return sort.Search(len(bounds), func(i int) bool { return bounds[i] > r }) - 1
+	}
+
+	// Subset construction over the intervals
+	var (
+		sets   [][]int
+		trans  [][]int
+		accept []int
+	)
+	index := map[string]int{}
+	state := func(set []int) int {
+		key := setKey(set)
+		if s, ok := index[key]; ok == true {
+			return s
+		}
+		s := len(sets)
+		index[key] = s
+		sets = append(sets, set)
+		row := make([]int, len(bounds))
+		for i := range row {
+			row[i] = -1
+		}
+		trans = append(trans, row)
+		a := -1
+		for _, ns := range set {
+			if ns := n.states[ns]; ns.accept >= 0 && (a < 0 || ns.accept < a) {
+				a = ns.accept
+			}
+		}
+		accept = append(accept, a)
+		return s
+	}
+	state(n.closure([]int{start}))
+	for s := 0; s < len(sets); s++ {
+		moves := make([][]int, len(bounds))
+		for _, ns := range sets[s] {
+			ranges := n.states[ns].ranges
+			for i := 0; i < len(ranges); i += 2 {
+				for k := interval(ranges[i]); k <= interval(ranges[i+1]); k++ {
+					moves[k] = append(moves[k], n.states[ns].next)
+				}
+			}
+		}
+		for k, move := range moves {
+			if len(move) > 0 {
+				trans[s][k] = state(n.closure(move))
+			}
+		}
+	}
+	return minimize(rules, bounds, trans, accept), nil
+}
+
+// minimize merges equivalent states (Moore's algorithm), drops states that can never
+// reach an accepting state and merges intervals with identical transitions into classes
+func minimize(rules []Rule, bounds []rune, trans [][]int, accept []int) *DFA {
+	// States that can reach an accepting state, the rest are dead
+	live := make([]bool, len(trans))
+	for changed := true; changed == true; {
+		changed = false
+		for s, row := range trans {
+			if live[s] == true {
+				continue
+			}
+			if accept[s] >= 0 {
+				live[s], changed = true, true
+				continue
+			}
+			for _, t := range row {
+				if t >= 0 && live[t] == true {
+					live[s], changed = true, true
+					break
+				}
+			}
+		}
+	}
+	target := func(block []int, t int) int {
+		if t < 0 || live[t] == false {
+			return -1
+		}
+		return block[t]
+	}
+
+	// Refine blocks starting from the accepted rule until stable
+	block := make([]int, len(trans))
+	count := 0
+	ids := map[int]int{}
+	for s, a := range accept {
+		if _, ok := ids[a]; ok == false {
+			ids[a] = len(ids)
+		}
+		block[s] = ids[a]
+	}
+	count = len(ids)
+	for {
+		next := make([]int, len(trans))
+		signatures := map[string]int{}
+		sig := make([]int, len(bounds)+1)
+		for s, row := range trans {
+			sig[0] = block[s]
+			for k, t := range row {
+				sig[k+1] = target(block, t)
+			}
+			key := setKey(sig)
+			if _, ok := signatures[key]; ok == false {
+				signatures[key] = len(signatures)
+			}
+			next[s] = signatures[key]
+		}
+		block = next
+		if len(signatures) == count {
+			break
+		}
+		count = len(signatures)
+	}
+
+	// Merge intervals whose columns are the same in every state into classes
+	columns := map[string]int{}
+	classOf := make([]int, len(bounds))
+	col := make([]int, count)
+	for k := range bounds {
+		for i := range col {
+			col[i] = -2
+		}
+		for s, row := range trans {
+			col[block[s]] = target(block, row[k])
+		}
+		key := setKey(col)
+		if _, ok := columns[key]; ok == false {
+			columns[key] = len(columns)
+		}
+		classOf[k] = columns[key]
+	}
+
+	d := &DFA{
+		Rules:      rules,
+		NumClasses: len(columns),
+		Trans:      make([]int, count*len(columns)),
+		Accept:     make([]int, count),
+		Start:      block[0],
+	}
+	for k, r := range bounds {
+		if k > 0 && classOf[k] == classOf[k-1] {
+			continue
+		}
+		d.Bounds = append(d.Bounds, r)
+		d.Classes = append(d.Classes, classOf[k])
+	}
+	for s, row := range trans {
+		d.Accept[block[s]] = accept[s]
+		for k, t := range row {
+			d.Trans[block[s]*d.NumClasses+classOf[k]] = target(block, t)
+		}
+	}
+	d.init()
+	return d
+}
+
+// init fills in the cached ASCII classes
+func (d *DFA) init() {
+	for r := range d.ascii {
+		d.ascii[r] = d.classOf(rune(r))
+	}
+}
+
+// classOf returns the input class of r
+func (d *DFA) classOf(r rune) int {
+	i := sort.Search(len(d.Bounds), func(i int) bool { return d.Bounds[i] > r }) - 1
+	return d.Classes[i]
+}
+
+// NumStates returns the number of states in the DFA
+func (d *DFA) NumStates() int {
+	return len(d.Accept)
+}
+
+// Match returns the index of the rule matching the longest prefix of buf and its length in bytes,
+// the rule index is -1 if nothing matches
+func (d *DFA) Match(buf []byte) (int, int) {
+	var (
+		r    rune
+		size int
+		c    int
+	)
+	rule, length := -1, 0
+	state := d.Start
+	for i := 0; i < len(buf); i += size {
+		if buf[i] < utf8.RuneSelf {
+			c, size = d.ascii[buf[i]], 1
+		} else {
+			r, size = utf8.DecodeRune(buf[i:])
+			c = d.classOf(r)
+		}
+		state = d.Trans[state*d.NumClasses+c]
+		if state < 0 {
+			break
+		}
+		if d.Accept[state] >= 0 {
+			rule, length = d.Accept[state], i+size
+		}
+	}
+	return rule, length
+}
+
+// Tok returns the next token matched by the rules and the remaining []byte
+func (d *DFA) Tok(buf []byte) (*Token, []byte) {
+	return d.TokAt(buf, StartPosition())
+}
+
+// TokAt is like Tok where buf starts at pos
+func (d *DFA) TokAt(buf []byte, pos Position) (*Token, []byte) {
+	for {
+		rule, length := d.Match(buf)
+		if rule < 0 {
+			return TokAt(buf, pos)
+		}
+		value := buf[0:length]
+		if d.Rules[rule].Skip == true {
+			pos = pos.Advance(value)
+			buf = buf[length:]
+			continue
+		}
+		return &Token{
+			Type:  d.Rules[rule].Type,
+			Value: value,
+			Pos:   pos.orStart(),
+		}, buf[length:]
+	}
+}
+
+// Tokenizer re-scans tok and buf with the DFA, it can be passed to Tok2() or Skip2()
+func (d *DFA) Tokenizer(tok *Token, buf []byte) (*Token, []byte) {
+	if tok.Type == EOF {
+		return tok, buf
+	}
+	return d.TokAt(join(tok.Value, buf), tok.Pos)
+}
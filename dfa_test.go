@@ -0,0 +1,152 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+var testRules = []Rule{
+	{Type: "Keyword", Pattern: `if|else|for`},
+	{Type: "Ident", Pattern: `[\pL_][\pL\pN_]*`},
+	{Type: "Number", Pattern: `[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?`},
+	{Type: "String", Pattern: `"([^"\\]|\\.)*"`},
+	{Type: "Operator", Pattern: `==|=|\+|-|\.\.\.|\.`},
+	{Type: "Select", Pattern: `(?i)select`},
+	{Type: Space, Pattern: `\s+`, Skip: true},
+}
+
+func TestDFA(t *testing.T) {
+	d, err := CompileDFA(testRules...)
+	if err != nil {
+		t.Errorf("CompileDFA() failed, %s", err)
+		t.FailNow()
+	}
+	rl, err := NewRuleLexer(testRules...)
+	if err != nil {
+		t.Errorf("NewRuleLexer() failed, %s", err)
+		t.FailNow()
+	}
+	src := []byte(`if iffy == 3.14e-2 + "a \"quoted\" café" else SeLeCt ... x.y 東京 #`)
+	buf1, buf2 := src, src
+	var token1, token2 *Token
+	for i := 0; ; i++ {
+		token1, buf1 = d.Tok(buf1)
+		token2, buf2 = rl.Tok(buf2)
+		if token1.Type != token2.Type || bytes.Equal(token1.Value, token2.Value) == false || token1.Pos != token2.Pos {
+			t.Errorf("%d: DFA %s at %s != RuleLexer %s at %s", i, token1, token1.Pos, token2, token2.Pos)
+		}
+		if token1.Type == EOF || token2.Type == EOF {
+			break
+		}
+	}
+
+	// Equivalent states are merged, after "a" or "c" only "b" is left to match
+	small, err := CompileDFA(Rule{Type: "X", Pattern: `ab|cb|(c)(b)`})
+	if err != nil {
+		t.Errorf("CompileDFA() failed, %s", err)
+	} else if small.NumStates() != 3 {
+		t.Errorf("expected a minimized DFA of 3 states, found %d", small.NumStates())
+	}
+
+	token, rest := Tok2([]byte("  for x"), d.Tokenizer)
+	if token.Type != "Keyword" || string(token.Value) != "for" || string(rest) != " x" {
+		t.Errorf("expected {Keyword: for}, found %s [%s]", token, rest)
+	}
+
+	if _, err := CompileDFA(Rule{Type: "Anchored", Pattern: `^a`}); err == nil {
+		t.Errorf("expected an error for an anchored pattern")
+	}
+	if _, err := CompileDFA(Rule{Type: "Empty", Pattern: `a?`}); err == nil {
+		t.Errorf("expected an error for a pattern matching the empty string")
+	}
+}
+
+func benchmarkSource(b *testing.B) []byte {
+	fname := path.Join("testdata", "sample-00.txt")
+	src, err := ioutil.ReadFile(fname)
+	if err != nil {
+		b.Fatalf("%s, %s", fname, err)
+	}
+	return bytes.Repeat(src, 100)
+}
+
+func BenchmarkTokWords(b *testing.B) {
+	src := benchmarkSource(b)
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var token *Token
+		for buf := src; len(buf) > 0; {
+			token, buf = Tok2(buf, Words)
+		}
+		_ = token
+	}
+}
+
+func BenchmarkRuleLexer(b *testing.B) {
+	src := benchmarkSource(b)
+	rl, err := NewRuleLexer(testRules...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var token *Token
+		for buf := src; len(buf) > 0; {
+			token, buf = rl.Tok(buf)
+		}
+		_ = token
+	}
+}
+
+func BenchmarkDFA(b *testing.B) {
+	src := benchmarkSource(b)
+	d, err := CompileDFA(testRules...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(src)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var token *Token
+		for buf := src; len(buf) > 0; {
+			token, buf = d.Tok(buf)
+		}
+		_ = token
+	}
+}
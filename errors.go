@@ -0,0 +1,94 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"fmt"
+)
+
+// MissingOpenError is returned by Between when the buffer ends before an opening delimiter is found
+type MissingOpenError struct {
+	Open []byte
+	// Pos is the end of the buffer
+	Pos Position
+}
+
+// Error implements the error interface
+func (e *MissingOpenError) Error() string {
+	return fmt.Sprintf("missing opening %s before %s", e.Open, e.Pos)
+}
+
+// UnterminatedError is returned by Between when the buffer ends before the closing delimiter
+type UnterminatedError struct {
+	Open  []byte
+	Close []byte
+	// OpenPos is the position of the outermost opening delimiter
+	OpenPos Position
+	// Pos is the end of the buffer
+	Pos Position
+	// Depth is the number of delimiters left open
+	Depth int
+}
+
+// Error implements the error interface
+func (e *UnterminatedError) Error() string {
+	return fmt.Sprintf("missing closing %s for %s at %s", e.Close, e.Open, e.OpenPos)
+}
+
+// UnexpectedCloseError is returned by Between when a closing delimiter is found before an opening one
+type UnexpectedCloseError struct {
+	Close []byte
+	// Pos is the position of the closing delimiter
+	Pos Position
+}
+
+// Error implements the error interface
+func (e *UnexpectedCloseError) Error() string {
+	return fmt.Sprintf("unexpected closing %s at %s", e.Close, e.Pos)
+}
+
+// EscapeAtEndError is returned by Between when the buffer ends immediately after an escape
+type EscapeAtEndError struct {
+	Escape []byte
+	// OpenPos is the position of the outermost opening delimiter
+	OpenPos Position
+	// Pos is the position of the escape
+	Pos Position
+	// Depth is the number of delimiters open at the escape
+	Depth int
+}
+
+// Error implements the error interface
+func (e *EscapeAtEndError) Error() string {
+	return fmt.Sprintf("escape %s at %s ends the input", e.Escape, e.Pos)
+}
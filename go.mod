@@ -0,0 +1,3 @@
+module github.com/rsdoiel/tok
+
+go 1.23
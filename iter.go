@@ -0,0 +1,129 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"iter"
+)
+
+// All returns an iterator over the tokens of buf produced by Tok(), the final EOF
+// token is not included. Token positions are tracked through buf.
+func All(buf []byte) iter.Seq[*Token] {
+	return AllWith(buf, func(token *Token, buf []byte) (*Token, []byte) {
+		return token, buf
+	})
+}
+
+// AllWith is like All but produces tokens with Tok2() and fn
+func AllWith(buf []byte, fn Tokenizer) iter.Seq[*Token] {
+	return func(yield func(*Token) bool) {
+		var token *Token
+		// Each range over the iterator starts again from the beginning of buf
+		rest := buf
+		pos := StartPosition()
+		for {
+			token, rest = Tok2At(rest, fn, pos)
+			if token.Type == EOF || yield(token) == false {
+				return
+			}
+			pos = token.End()
+		}
+	}
+}
+
+// Lines returns an iterator over the lines of buf as split by NextLine(), yielding
+// the line number (starting at 1) and the line without its line ending
+func Lines(buf []byte) iter.Seq2[int, []byte] {
+	return func(yield func(int, []byte) bool) {
+		var line []byte
+		rest := buf
+		for i := 1; len(rest) > 0; i++ {
+			line, rest = NextLine(rest)
+			if yield(i, line) == false {
+				return
+			}
+		}
+	}
+}
+
+// Filter returns an iterator over the tokens of seq for which keep returns true
+func Filter(seq iter.Seq[*Token], keep func(*Token) bool) iter.Seq[*Token] {
+	return func(yield func(*Token) bool) {
+		for token := range seq {
+			if keep(token) == true && yield(token) == false {
+				return
+			}
+		}
+	}
+}
+
+// Only returns an iterator over the tokens of seq whose Type is one of tokenTypes
+func Only(seq iter.Seq[*Token], tokenTypes ...string) iter.Seq[*Token] {
+	return Filter(seq, func(token *Token) bool {
+		return hasType(token, tokenTypes)
+	})
+}
+
+// Drop returns an iterator over the tokens of seq whose Type is not one of tokenTypes
+// (e.g. Drop(All(buf), Space) to ignore white space)
+func Drop(seq iter.Seq[*Token], tokenTypes ...string) iter.Seq[*Token] {
+	return Filter(seq, func(token *Token) bool {
+		return hasType(token, tokenTypes) == false
+	})
+}
+
+// hasType reports if token's Type is one of tokenTypes
+func hasType(token *Token, tokenTypes []string) bool {
+	for _, tokenType := range tokenTypes {
+		if token.Type == tokenType {
+			return true
+		}
+	}
+	return false
+}
+
+// All returns an iterator over the remaining tokens of the Scanner, the final EOF
+// token is not included. Check Err() once the loop ends.
+func (s *Scanner) All() iter.Seq[*Token] {
+	return func(yield func(*Token) bool) {
+		for {
+			token := s.Next()
+			if token.Type == EOF {
+				return
+			}
+			if yield(token) == false {
+				return
+			}
+		}
+	}
+}
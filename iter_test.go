@@ -0,0 +1,126 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	buf := []byte("one, two\nthree")
+	values := []string{}
+	for token := range Drop(AllWith(buf, Words), Space, Punctuation) {
+		values = append(values, string(token.Value))
+	}
+	if strings.Join(values, "|") != "one|two|three" {
+		t.Errorf("expected one|two|three, found %s", strings.Join(values, "|"))
+	}
+
+	count := 0
+	for token := range All(buf) {
+		count++
+		if count == 3 {
+			if string(token.Value) != "e" || token.Pos.Column != 3 {
+				t.Errorf("expected e at column 3, found %s at %s", token, token.Pos)
+			}
+			break
+		}
+	}
+	if count != 3 {
+		t.Errorf("expected to stop after 3 tokens, found %d", count)
+	}
+
+	for token := range Only(AllWith(buf, Words), Word) {
+		if token.Type != Word {
+			t.Errorf("expected only Word tokens, found %s", token)
+		}
+		if string(token.Value) == "three" && token.Pos.Line != 2 {
+			t.Errorf("expected three on line 2, found %s", token.Pos)
+		}
+	}
+
+	scanner := NewScanner(strings.NewReader("a b"))
+	values = values[:0]
+	for token := range Only(scanner.All(), Letter) {
+		values = append(values, string(token.Value))
+	}
+	if strings.Join(values, "") != "ab" {
+		t.Errorf("expected ab, found %v", values)
+	}
+}
+
+func TestLines(t *testing.T) {
+	expected := []string{"one", "two and three", "four"}
+	i := 0
+	for n, line := range Lines([]byte("one\ntwo and three\r\nfour\n")) {
+		if i >= len(expected) {
+			t.Errorf("unexpected line %d %q", n, line)
+			break
+		}
+		if n != i+1 || string(line) != expected[i] {
+			t.Errorf("expected %d %q, found %d %q", i+1, expected[i], n, line)
+		}
+		i++
+	}
+	if i != len(expected) {
+		t.Errorf("expected %d lines, found %d", len(expected), i)
+	}
+	for n := range Lines([]byte("a\nb\nc")) {
+		if n > 1 {
+			t.Errorf("expected break to stop the iterator")
+		}
+		break
+	}
+}
+
+func TestIteratorsReusable(t *testing.T) {
+	buf := []byte("a b\nc")
+	all, lines := All(buf), Lines(buf)
+	for pass := 1; pass <= 2; pass++ {
+		count := 0
+		for range all {
+			count++
+		}
+		if count != 5 {
+			t.Errorf("pass %d: expected 5 tokens, found %d", pass, count)
+		}
+		count = 0
+		for range lines {
+			count++
+		}
+		if count != 2 {
+			t.Errorf("pass %d: expected 2 lines, found %d", pass, count)
+		}
+	}
+}
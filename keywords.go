@@ -0,0 +1,176 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CaseMode controls how Keywords compare words
+type CaseMode int
+
+const (
+	// CaseSensitive matches words exactly (e.g. Go's "if")
+	CaseSensitive CaseMode = iota
+	// CaseInsensitive ignores the case of ASCII letters (e.g. SQL's "SELECT" and "select")
+	CaseInsensitive
+	// CaseFolded ignores case using Unicode simple case folding as strings.EqualFold() does
+	CaseFolded
+)
+
+// keyword is an entry of the Keywords table, word is normalized by the table's CaseMode
+type keyword struct {
+	word      string
+	tokenType string
+}
+
+// Keywords is a table of keywords and reserved words used to retype words (e.g. "if" as "If",
+// "true" as "Boolean"). It is kept sorted for binary search and lookups don't allocate.
+// Add keywords before use, after that it is safe for concurrent use.
+type Keywords struct {
+	mode    CaseMode
+	entries []keyword
+}
+
+// NewKeywords returns an empty keyword table comparing words by mode
+func NewKeywords(mode CaseMode) *Keywords {
+	return &Keywords{
+		mode: mode,
+	}
+}
+
+// normalize returns r as it is compared by the table's CaseMode
+func (k *Keywords) normalize(r rune) rune {
+	switch k.mode {
+	case CaseInsensitive:
+		if r >= 'A' && r <= 'Z' {
+			return r + ('a' - 'A')
+		}
+	case CaseFolded:
+		// The smallest member of r's case folding orbit (e.g. 'K' for 'k' and the Kelvin sign)
+		min := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if f < min {
+				min = f
+			}
+		}
+		return min
+	}
+	return r
+}
+
+// compare returns -1, 0 or 1 as word sorts before, equal to or after value normalized
+func (k *Keywords) compare(word string, value []byte) int {
+	for {
+		switch {
+		case len(word) == 0 && len(value) == 0:
+			return 0
+		case len(word) == 0:
+			return -1
+		case len(value) == 0:
+			return 1
+		}
+		a, n := utf8.DecodeRuneInString(word)
+		b, m := utf8.DecodeRune(value)
+		b = k.normalize(b)
+		if a != b {
+			if a < b {
+				return -1
+			}
+			return 1
+		}
+		word, value = word[n:], value[m:]
+	}
+}
+
+// search returns the index of the first entry not sorting before value
+func (k *Keywords) search(value []byte) int {
+	return sort.Search(len(k.entries), func(i int) bool {
+		return k.compare(k.entries[i].word, value) >= 0
+	})
+}
+
+// Add registers words as keywords of tokenType, adding a word again replaces its type
+func (k *Keywords) Add(tokenType string, words ...string) {
+	for _, word := range words {
+		normalized := make([]rune, 0, len(word))
+		for _, r := range word {
+			normalized = append(normalized, k.normalize(r))
+		}
+		entry := keyword{word: string(normalized), tokenType: tokenType}
+		i := k.search([]byte(entry.word))
+		if i < len(k.entries) && k.entries[i].word == entry.word {
+			k.entries[i] = entry
+			continue
+		}
+		k.entries = append(k.entries, keyword{})
+		copy(k.entries[i+1:], k.entries[i:])
+		k.entries[i] = entry
+	}
+}
+
+// Len returns the number of keywords
+func (k *Keywords) Len() int {
+	return len(k.entries)
+}
+
+// Lookup returns the token type of the keyword matching value
+func (k *Keywords) Lookup(value []byte) (string, bool) {
+	i := k.search(value)
+	if i < len(k.entries) && k.compare(k.entries[i].word, value) == 0 {
+		return k.entries[i].tokenType, true
+	}
+	return "", false
+}
+
+// Retype returns a copy of token with its keyword's type, or token if its value isn't a keyword
+func (k *Keywords) Retype(token *Token) *Token {
+	tokenType, ok := k.Lookup(token.Value)
+	if ok == false {
+		return token
+	}
+	retyped := *token
+	retyped.Type = tokenType
+	return &retyped
+}
+
+// Tokenizer retypes Word and Letter tokens that are keywords, other tokens are returned as is.
+// Use it after a Tokenizer assembling words, e.g. Tok2(buf, Chain(Words, keywords.Tokenizer)).
+func (k *Keywords) Tokenizer(tok *Token, buf []byte) (*Token, []byte) {
+	if tok.Type != Word && tok.Type != Letter {
+		return tok, buf
+	}
+	return k.Retype(tok), buf
+}
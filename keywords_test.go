@@ -0,0 +1,111 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"testing"
+)
+
+func TestKeywords(t *testing.T) {
+	sensitive := NewKeywords(CaseSensitive)
+	sensitive.Add("Keyword", "if", "else", "for", "func")
+	sensitive.Add("Boolean", "true", "false")
+	sensitive.Add("Keyword", "true")
+	if sensitive.Len() != 6 {
+		t.Errorf("expected 6 keywords, found %d", sensitive.Len())
+	}
+	for value, expected := range map[string]string{
+		"if": "Keyword", "func": "Keyword", "false": "Boolean", "true": "Keyword", "If": "", "fun": "", "funcs": "", "": "",
+	} {
+		tokenType, ok := sensitive.Lookup([]byte(value))
+		if tokenType != expected || ok != (expected != "") {
+			t.Errorf("expected %q for %q, found %q %t", expected, value, tokenType, ok)
+		}
+	}
+
+	insensitive := NewKeywords(CaseInsensitive)
+	insensitive.Add("Select", "SELECT")
+	insensitive.Add("From", "from")
+	for _, value := range []string{"select", "SELECT", "Select", "FROM", "From"} {
+		if _, ok := insensitive.Lookup([]byte(value)); ok == false {
+			t.Errorf("expected %q to be a keyword", value)
+		}
+	}
+	if _, ok := insensitive.Lookup([]byte("ſelect")); ok == true {
+		t.Errorf("expected ASCII only case insensitivity")
+	}
+
+	folded := NewKeywords(CaseFolded)
+	folded.Add("Street", "straße")
+	folded.Add("Kelvin", "k")
+	for _, value := range []string{"STRAßE", "Straße", "straße", "K", "K"} {
+		if _, ok := folded.Lookup([]byte(value)); ok == false {
+			t.Errorf("expected %q to be a keyword", value)
+		}
+	}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		folded.Lookup([]byte("STRAßE"))
+		insensitive.Lookup([]byte("Select"))
+	})
+	if allocs != 0 {
+		t.Errorf("expected lookups not to allocate, found %v", allocs)
+	}
+}
+
+func TestKeywordsTokenizer(t *testing.T) {
+	keywords := NewKeywords(CaseInsensitive)
+	keywords.Add("Select", "select")
+	keywords.Add("From", "from")
+	keywords.Add("Alias", "a")
+	ops := NewOperators()
+	ops.Add("NotEqual", []byte("<>"))
+
+	src := []byte("SELECT name FROM t a WHERE x <> 10")
+	expected := []string{"Select", Word, "From", Letter, "Alias", Word, Letter, "NotEqual", Integer}
+	i := 0
+	for token := range Drop(AllWith(src, Chain(Words, Numbers, ops.Tokenizer, keywords.Tokenizer)), Space) {
+		if i < len(expected) && token.Type != expected[i] {
+			t.Errorf("(%d) expected %s, found %s", i, expected[i], token)
+		}
+		i++
+	}
+	if i != len(expected) {
+		t.Errorf("expected %d tokens, found %d", len(expected), i)
+	}
+
+	token := keywords.Retype(&Token{Type: "Identifier", Value: []byte("From")})
+	if token.Type != "From" {
+		t.Errorf("expected Retype to work on any token, found %s", token)
+	}
+}
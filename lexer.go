@@ -0,0 +1,262 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"io"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Lexer holds the character classes and token map used to classify each UTF-8 code point.
+// Configure a Lexer before use, after that it is safe for concurrent use. The package
+// level functions (e.g. Tok(), Peek(), Skip(), Between()) use a default Lexer made by NewLexer().
+type Lexer struct {
+	// Overrides maps individual code points to a token type, checked before the character classes
+	// (e.g. map[rune]string{'_': Letter})
+	Overrides map[rune]string
+	// PunctuationMarks are the code points returned as Punctuation
+	PunctuationMarks []*unicode.RangeTable
+	// Spaces are the code points returned as Space
+	Spaces []*unicode.RangeTable
+	// Numerals are the code points returned as Numeral
+	Numerals []*unicode.RangeTable
+	// Symbols are the code points returned as Symbol
+	Symbols []*unicode.RangeTable
+	// TokenMap, if not nil, is used to revalue each token with TokenFromMap(), its types are
+	// sorted once when the Lexer is first used
+	TokenMap TokenMap
+	// MaxTokenLength, if greater than zero, is the most bytes Words() accumulates into one token
+	// and Skip() or Skip2() accumulate into skipped content
+	MaxTokenLength int
+	// RejectTooLong makes Words() return a TooLong token rather than split a word at MaxTokenLength
+	RejectTooLong bool
+
+	mapOnce sync.Once
+	mapKeys []string
+}
+
+// defaultLexer is used by the package level functions
+var defaultLexer = NewLexer()
+
+// NewLexer returns a Lexer that classifies code points by their Unicode category,
+// matching Tok(). Code points outside of the character classes are returned as Letter.
+func NewLexer() *Lexer {
+	return &Lexer{
+		PunctuationMarks: []*unicode.RangeTable{unicode.Punct, asciiSymbols},
+		Spaces:           []*unicode.RangeTable{unicode.White_Space},
+		Numerals:         []*unicode.RangeTable{unicode.Digit},
+		Symbols:          []*unicode.RangeTable{unicode.Symbol},
+	}
+}
+
+// typeOf returns the base token type of a single UTF-8 code point
+func (l *Lexer) typeOf(s []byte) string {
+	r, size := utf8.DecodeRune(s)
+	if r == utf8.RuneError && size <= 1 {
+		return Invalid
+	}
+	if tokenType, ok := l.Overrides[r]; ok == true {
+		return tokenType
+	}
+	switch {
+	case unicode.IsOneOf(l.PunctuationMarks, r):
+		return Punctuation
+	case unicode.IsOneOf(l.Spaces, r):
+		return Space
+	case unicode.IsOneOf(l.Numerals, r):
+		return Numeral
+	case unicode.IsOneOf(l.Symbols, r):
+		return Symbol
+	default:
+		return Letter
+	}
+}
+
+// tokenType returns the type TokAt() gives the code point s, including the TokenMap
+func (l *Lexer) tokenType(s []byte) string {
+	tokenType := l.typeOf(s)
+	if l.TokenMap == nil {
+		return tokenType
+	}
+	return mapType(l.TokenMap, l.tokenMapKeys(), s, tokenType)
+}
+
+// tokenMapKeys returns the TokenMap types in sorted order, sorting them on first use
+func (l *Lexer) tokenMapKeys() []string {
+	l.mapOnce.Do(func() {
+		l.mapKeys = sortedKeys(l.TokenMap)
+	})
+	return l.mapKeys
+}
+
+// Tok is like the package level Tok() using the Lexer's character classes
+func (l *Lexer) Tok(buf []byte) (*Token, []byte) {
+	return l.TokAt(buf, StartPosition())
+}
+
+// TokAt is like Tok where buf starts at pos
+func (l *Lexer) TokAt(buf []byte, pos Position) (*Token, []byte) {
+	var (
+		s     []byte
+		token *Token
+	)
+	pos = pos.orStart()
+	if len(buf) == 0 {
+		return &Token{
+			Type:  EOF,
+			Value: []byte(""),
+			Pos:   pos,
+		}, nil
+	}
+	_, size := utf8.DecodeRune(buf)
+	s, buf = buf[0:size], buf[size:]
+	token = &Token{
+		Type:  l.tokenType(s),
+		Value: s,
+		Pos:   pos,
+	}
+	return token, buf
+}
+
+// Tok2 is like the package level Tok2() using the Lexer's character classes
+func (l *Lexer) Tok2(buf []byte, fn Tokenizer) (*Token, []byte) {
+	return l.Tok2At(buf, fn, StartPosition())
+}
+
+// Tok2At is like Tok2 where buf starts at pos
+func (l *Lexer) Tok2At(buf []byte, fn Tokenizer, pos Position) (*Token, []byte) {
+	tok, rest := l.TokAt(buf, pos)
+	return fn(tok, rest)
+}
+
+// Peek is like the package level Peek() using the Lexer's character classes
+func (l *Lexer) Peek(buf []byte) *Token {
+	return l.PeekAt(buf, StartPosition())
+}
+
+// PeekAt is like Peek where buf starts at pos
+func (l *Lexer) PeekAt(buf []byte, pos Position) *Token {
+	token, _ := l.TokAt(buf, pos)
+	return token
+}
+
+// Skip is like the package level Skip() using the Lexer's character classes
+func (l *Lexer) Skip(tokenType string, buf []byte) ([]byte, *Token, []byte) {
+	return l.SkipAt(tokenType, buf, StartPosition())
+}
+
+// SkipAt is like Skip where buf starts at pos
+func (l *Lexer) SkipAt(tokenType string, buf []byte, pos Position) ([]byte, *Token, []byte) {
+	return l.Skip2At(tokenType, buf, func(token *Token, buf []byte) (*Token, []byte) {
+		return token, buf
+	}, pos)
+}
+
+// Skip2 is like the package level Skip2() using the Lexer's character classes
+func (l *Lexer) Skip2(tokenType string, buf []byte, fn Tokenizer) ([]byte, *Token, []byte) {
+	return l.Skip2At(tokenType, buf, fn, StartPosition())
+}
+
+// Skip2At is like Skip2 where buf starts at pos. If the skipped content would pass MaxTokenLength
+// skipping stops early, the token returned is an empty TooLong token where skipping stopped and
+// the remaining buffer starts there so skipping can be resumed.
+func (l *Lexer) Skip2At(tokenType string, buf []byte, fn Tokenizer, pos Position) ([]byte, *Token, []byte) {
+	var (
+		skipped []byte
+		token   *Token
+	)
+	// Handle an empty buffer gracefully
+	if len(buf) == 0 {
+		token, buf = l.TokAt(buf, pos)
+		return skipped, token, buf
+	}
+	for {
+		rest := buf
+		token, buf = l.Tok2At(buf, fn, pos)
+		if token.Type != tokenType {
+			break
+		}
+		if l.MaxTokenLength > 0 && len(skipped)+len(token.Value) > l.MaxTokenLength {
+			return skipped, &Token{Type: TooLong, Value: []byte{}, Pos: pos}, rest
+		}
+		skipped = join(skipped, token.Value)
+		pos = token.End()
+		if len(buf) == 0 {
+			break
+		}
+	}
+
+	return skipped, token, buf
+}
+
+// Words is like the package level Words() using the Lexer's character classes. Letters are
+// accumulated in a single pass, when tok.Value aliases buf (as from Tok()) the word is sliced
+// from buf without copying. With MaxTokenLength set a longer run of letters is split into
+// several words, or returned as a TooLong token when RejectTooLong is true, the remaining letters
+// are left in the buffer.
+func (l *Lexer) Words(tok *Token, buf []byte) (*Token, []byte) {
+	if tok.Type != Letter && tok.Type != Word {
+		return tok, buf
+	}
+	n, tooLong := 0, false
+	for n < len(buf) {
+		_, size := utf8.DecodeRune(buf[n:])
+		if l.tokenType(buf[n:n+size]) != Letter {
+			break
+		}
+		if l.MaxTokenLength > 0 && len(tok.Value)+n+size > l.MaxTokenLength {
+			tooLong = true
+			break
+		}
+		n += size
+	}
+	if n > 0 {
+		tok.Type = Word
+		tok.Value = join(tok.Value, buf[0:n])
+	}
+	if tooLong == true && l.RejectTooLong == true {
+		tok.Type = TooLong
+	}
+	return tok, buf[n:]
+}
+
+// NewScanner returns a Scanner reading from r using the Lexer's character classes
+func (l *Lexer) NewScanner(r io.Reader) *Scanner {
+	return &Scanner{
+		r:     r,
+		pos:   StartPosition(),
+		lexer: l,
+	}
+}
@@ -0,0 +1,178 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"unicode"
+)
+
+func TestLexer(t *testing.T) {
+	src := []byte("snake_case, x")
+
+	lexer := NewLexer()
+	lexer.Overrides = map[rune]string{'_': Letter}
+	_, token, _ := lexer.Skip2(Space, src, lexer.Words)
+	if token.Type != Word || string(token.Value) != "snake_case" {
+		t.Errorf("expected {%q: %q}, found %s", Word, "snake_case", token)
+	}
+
+	// The default lexer is not changed by configuring another Lexer
+	_, token, _ = Skip2(Space, src, Words)
+	if token.Type != Word || string(token.Value) != "snake" {
+		t.Errorf("expected {%q: %q}, found %s", Word, "snake", token)
+	}
+
+	// Character classes can be replaced, here only ASCII space counts as Space
+	lexer = NewLexer()
+	lexer.Spaces = []*unicode.RangeTable{{R16: []unicode.Range16{{Lo: ' ', Hi: ' ', Stride: 1}}, LatinOffset: 1}}
+	if token := lexer.Peek([]byte("\t")); token.Type != Letter {
+		t.Errorf("expected tab to fall through to Letter, found %s", token)
+	}
+	if token := lexer.Peek([]byte(" ")); token.Type != Space {
+		t.Errorf("expected space to be Space, found %s", token)
+	}
+
+	lexer = NewLexer()
+	lexer.TokenMap = TokenMap{AtSign: AtSignMark}
+	token, _ = lexer.Tok([]byte("@me"))
+	if token.Type != AtSign {
+		t.Errorf("expected %s, found %s", AtSign, token)
+	}
+	// The TokenMap types are sorted once, not per token
+	var into Token
+	at := []byte("@me")
+	if allocs := testing.AllocsPerRun(10, func() { lexer.TokInto(&into, at, StartPosition()) }); allocs != 0 {
+		t.Errorf("expected TokInto with a TokenMap not to allocate, found %.0f allocations", allocs)
+	}
+
+	between, _, err := lexer.Between([]byte("{"), []byte("}"), []byte(""), []byte("a {b} c"))
+	if err != nil || string(between) != "b" {
+		t.Errorf("expected [b], found [%s], %v", between, err)
+	}
+
+	scanner := lexer.NewScanner(strings.NewReader("@"))
+	if token := scanner.Next(); token.Type != AtSign {
+		t.Errorf("expected Scanner to use the Lexer, found %s", token)
+	}
+}
+
+func TestLexerConcurrent(t *testing.T) {
+	lexer := NewLexer()
+	lexer.Overrides = map[rune]string{'-': Letter}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var (
+				token *Token
+				buf   []byte
+			)
+			if i%2 == 0 {
+				token, buf = lexer.Tok2([]byte("well-known"), lexer.Words)
+			} else {
+				token, buf = Tok2([]byte("well-known"), Words)
+			}
+			if i%2 == 0 && string(token.Value) != "well-known" {
+				t.Errorf("lexer expected well-known, found %s", token)
+			}
+			if i%2 == 1 && (string(token.Value) != "well" || string(buf) != "-known") {
+				t.Errorf("default expected well, found %s", token)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestWordsLongRun(t *testing.T) {
+	src := []byte(strings.Repeat("aé", 1<<17) + " b")
+	token, buf := Tok2(src, Words)
+	if token.Type != Word || len(token.Value) != 3<<17 {
+		t.Errorf("expected one Word of %d bytes, found %s of %d bytes", 3<<17, token.Type, len(token.Value))
+	}
+	if &token.Value[0] != &src[0] {
+		t.Errorf("expected the word to be sliced from the input")
+	}
+	if string(buf) != " b" {
+		t.Errorf("expected [ b], found [%s]", buf)
+	}
+	allocs := testing.AllocsPerRun(10, func() {
+		Tok2(src, Words)
+	})
+	if allocs > 1 {
+		t.Errorf("expected one allocation for the token, found %v", allocs)
+	}
+}
+
+func TestMaxTokenLength(t *testing.T) {
+	lexer := NewLexer()
+	lexer.MaxTokenLength = 4
+	expected := []string{"abcd", "ef", " ", "g"}
+	buf := []byte("abcdef g")
+	for i, value := range expected {
+		var token *Token
+		token, buf = lexer.Tok2(buf, lexer.Words)
+		if string(token.Value) != value {
+			t.Errorf("(%d) expected [%s], found [%s]", i, value, token.Value)
+		}
+	}
+
+	// A multi-byte code point is never split
+	token, buf := lexer.Tok2([]byte("abcé"), lexer.Words)
+	if string(token.Value) != "abc" || string(buf) != "é" {
+		t.Errorf("expected [abc] then [é], found [%s] then [%s]", token.Value, buf)
+	}
+
+	lexer.RejectTooLong = true
+	token, buf = lexer.Tok2([]byte("abcdef g"), lexer.Words)
+	if token.Type != TooLong || string(token.Value) != "abcd" || string(buf) != "ef g" {
+		t.Errorf("expected TooLong [abcd] then [ef g], found %s then [%s]", token, buf)
+	}
+	token, _ = lexer.Tok2([]byte("abcd e"), lexer.Words)
+	if token.Type != Word {
+		t.Errorf("expected a Word at the limit, found %s", token)
+	}
+
+	lexer.MaxTokenLength = 3
+	skipped, token, buf := lexer.Skip(Space, []byte("     x"))
+	if string(skipped) != "   " || token.Type != TooLong || token.Pos.Offset != 3 || string(buf) != "  x" {
+		t.Errorf("expected three spaces and a TooLong token at offset 3, found [%s] %s [%s]", skipped, token, buf)
+	}
+	skipped, token, _ = lexer.Skip(Space, buf)
+	if string(skipped) != "  " || token.Type != Letter {
+		t.Errorf("expected skipping to resume, found [%s] %s", skipped, token)
+	}
+}
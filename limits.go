@@ -0,0 +1,119 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"context"
+	"fmt"
+	"iter"
+)
+
+// contextInterval is how many steps a long running loop takes between checks of its context
+const contextInterval = 1024
+
+// Limits bound the work done tokenizing untrusted input, a zero field is unlimited
+type Limits struct {
+	// MaxBytes is the most bytes of input consumed
+	MaxBytes int
+	// MaxTokens is the most tokens produced
+	MaxTokens int
+	// MaxDepth is the deepest nesting of delimiters allowed by Between
+	MaxDepth int
+	// MaxTokenSize is the most bytes in a single token or Between result
+	MaxTokenSize int
+}
+
+// LimitError is returned when input exceeds one of the Limits
+type LimitError struct {
+	// Limit is the name of the field of Limits exceeded (e.g. "MaxBytes")
+	Limit string
+	// Max is the value of the limit
+	Max int
+	// Pos is where the limit was exceeded
+	Pos Position
+}
+
+// Error implements the error interface
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s limit of %d exceeded at %s", e.Limit, e.Max, e.Pos)
+}
+
+// check returns a *LimitError if value is over a non-zero max
+func (l Limits) check(limit string, max int, value int, pos Position) error {
+	if max > 0 && value > max {
+		return &LimitError{Limit: limit, Max: max, Pos: pos}
+	}
+	return nil
+}
+
+// token checks a token against MaxTokenSize, MaxTokens given the count of tokens so far
+// (including this one) and MaxBytes given the bytes consumed once it is done
+func (l Limits) token(token *Token, count int, consumed int) error {
+	if err := l.check("MaxTokenSize", l.MaxTokenSize, len(token.Value), token.Pos); err != nil {
+		return err
+	}
+	if err := l.check("MaxTokens", l.MaxTokens, count, token.Pos); err != nil {
+		return err
+	}
+	return l.check("MaxBytes", l.MaxBytes, consumed, token.Pos)
+}
+
+// AllContext is like AllWith but stops when ctx is done or buf exceeds limits, yielding
+// a nil token with ctx.Err() or a *LimitError as the last pair. Pair MaxTokenSize with a
+// Lexer's MaxTokenLength so a Tokenizer like Words never accumulates a huge token.
+func AllContext(ctx context.Context, buf []byte, fn Tokenizer, limits Limits) iter.Seq2[*Token, error] {
+	return func(yield func(*Token, error) bool) {
+		var token *Token
+		// Each range over the iterator starts again from the beginning of buf
+		rest := buf
+		pos := StartPosition()
+		for count := 1; ; count++ {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			token, rest = Tok2At(rest, fn, pos)
+			if token.Type == EOF {
+				return
+			}
+			pos = token.End()
+			if err := limits.token(token, count, pos.Offset); err != nil {
+				yield(nil, err)
+				return
+			}
+			if yield(token, nil) == false {
+				return
+			}
+		}
+	}
+}
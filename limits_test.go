@@ -0,0 +1,176 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAllContext(t *testing.T) {
+	ctx := context.Background()
+	src := []byte("one two three four")
+
+	count := 0
+	for token, err := range AllContext(ctx, src, Words, Limits{}) {
+		if err != nil {
+			t.Errorf("unexpected error %s", err)
+		}
+		if token.Type == Word {
+			count++
+		}
+	}
+	if count != 4 {
+		t.Errorf("expected 4 words, found %d", count)
+	}
+	seq := AllContext(ctx, src, Words, Limits{})
+	for pass := 1; pass <= 2; pass++ {
+		count = 0
+		for range seq {
+			count++
+		}
+		if count != 7 {
+			t.Errorf("pass %d: expected 7 tokens, found %d", pass, count)
+		}
+	}
+
+	for limits, name := range map[Limits]string{
+		{MaxTokens: 3}:    "MaxTokens",
+		{MaxBytes: 10}:    "MaxBytes",
+		{MaxTokenSize: 4}: "MaxTokenSize",
+	} {
+		var last error
+		for _, err := range AllContext(ctx, src, Words, limits) {
+			last = err
+		}
+		var limitErr *LimitError
+		if errors.As(last, &limitErr) == false || limitErr.Limit != name {
+			t.Errorf("expected a %s *LimitError, found %v", name, last)
+		}
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	for token, err := range AllContext(canceled, src, Words, Limits{}) {
+		if token != nil || errors.Is(err, context.Canceled) == false {
+			t.Errorf("expected context.Canceled, found %s %v", token, err)
+		}
+	}
+}
+
+func TestBetweenLimits(t *testing.T) {
+	open, close := []byte("{"), []byte("}")
+	_, _, err := BetweenWith(open, close, nil, []byte("{{{{x}}}}"), BetweenOptions{Limits: Limits{MaxDepth: 3}})
+	var limitErr *LimitError
+	if errors.As(err, &limitErr) == false || limitErr.Limit != "MaxDepth" || limitErr.Pos.Offset != 4 {
+		t.Errorf("expected MaxDepth exceeded at offset 4, found %v", err)
+	}
+
+	unterminated := []byte("{" + strings.Repeat("a", 10000))
+	_, _, err = BetweenWith(open, close, nil, unterminated, BetweenOptions{Limits: Limits{MaxBytes: 100}})
+	if errors.As(err, &limitErr) == false || limitErr.Limit != "MaxBytes" || limitErr.Pos.Offset != 101 {
+		t.Errorf("expected MaxBytes exceeded at offset 101, found %v", err)
+	}
+
+	between, _, err := BetweenWith(open, close, nil, unterminated, BetweenOptions{Recover: true, Limits: Limits{MaxTokenSize: 5}})
+	if errors.As(err, &limitErr) == false || limitErr.Limit != "MaxTokenSize" {
+		t.Errorf("expected MaxTokenSize exceeded, found %v", err)
+	}
+	if string(between) != "aaaaa" {
+		t.Errorf("expected a partial result in recovery mode, found [%s]", between)
+	}
+
+	// The closing delimiter of an inclusive result counts too
+	between, _, err = BetweenWith(open, close, nil, []byte("{abc}"), BetweenOptions{Recover: true, Inclusive: true, Limits: Limits{MaxTokenSize: 4}})
+	if errors.As(err, &limitErr) == false || string(between) != "{abc" {
+		t.Errorf("expected [{abc] and MaxTokenSize exceeded, found [%s] %v", between, err)
+	}
+	between, _, err = BetweenWith(open, close, nil, []byte("{abc}"), BetweenOptions{Inclusive: true, Limits: Limits{MaxTokenSize: 5}})
+	if err != nil || string(between) != "{abc}" {
+		t.Errorf("expected [{abc}] at the limit, found [%s] %v", between, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = BetweenWith(open, close, nil, unterminated, BetweenOptions{Context: ctx})
+	if errors.Is(err, context.Canceled) == false {
+		t.Errorf("expected context.Canceled, found %v", err)
+	}
+}
+
+func TestScannerLimits(t *testing.T) {
+	scanner := NewScanner(strings.NewReader("a b c d"))
+	scanner.SetLimits(nil, Limits{MaxTokens: 3})
+	values := []string{}
+	for token := range scanner.All() {
+		values = append(values, string(token.Value))
+	}
+	if strings.Join(values, "") != "a b" {
+		t.Errorf("expected [a b], found %q", values)
+	}
+	var limitErr *LimitError
+	if errors.As(scanner.Err(), &limitErr) == false || limitErr.Limit != "MaxTokens" {
+		t.Errorf("expected MaxTokens exceeded, found %v", scanner.Err())
+	}
+
+	// A huge word is stopped before it is read completely
+	reader := strings.NewReader(strings.Repeat("a", 1<<20))
+	scanner = NewScanner(reader)
+	scanner.SetLimits(nil, Limits{MaxTokenSize: 10000})
+	if token := scanner.Next2(Words); token.Type != EOF {
+		t.Errorf("expected EOF, found %s", token.Type)
+	}
+	if errors.As(scanner.Err(), &limitErr) == false || limitErr.Limit != "MaxTokenSize" {
+		t.Errorf("expected MaxTokenSize exceeded, found %v", scanner.Err())
+	}
+	if reader.Len() == 0 {
+		t.Errorf("expected the reader not to be exhausted")
+	}
+
+	scanner = NewScanner(strings.NewReader("{" + strings.Repeat("a", 1<<20)))
+	scanner.SetLimits(nil, Limits{MaxBytes: 5000})
+	if _, err := scanner.Between([]byte("{"), []byte("}"), nil); errors.As(err, &limitErr) == false || limitErr.Limit != "MaxBytes" {
+		t.Errorf("expected MaxBytes exceeded, found %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	scanner = NewScanner(strings.NewReader("a b"))
+	scanner.SetLimits(ctx, Limits{})
+	scanner.Next()
+	cancel()
+	if token := scanner.Next(); token.Type != EOF || errors.Is(scanner.Err(), context.Canceled) == false {
+		t.Errorf("expected EOF and context.Canceled, found %s %v", token, scanner.Err())
+	}
+}
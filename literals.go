@@ -0,0 +1,259 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"bytes"
+)
+
+const (
+	// String is a quoted string literal whose escapes are decoded (e.g. "a\"b", 'c', """doc""")
+	String = "String"
+	// RawString is a string literal without escapes (e.g. `raw`, r#"raw"#, [==[raw]==])
+	RawString = "RawString"
+	// Heredoc is a shell or Perl style here document (e.g. <<EOF ... EOF)
+	Heredoc = "Heredoc"
+)
+
+// literal returns a token of tokenType holding tok's value followed by n bytes of buf
+func literal(tok *Token, buf []byte, tokenType string, n int, decoded []byte) (*Token, []byte) {
+	return &Token{
+		Type:    tokenType,
+		Value:   join(tok.Value, buf[0:n]),
+		Pos:     tok.Pos,
+		Decoded: decoded,
+	}, buf[n:]
+}
+
+// decoded returns a String token for content decoded by unescape or an Invalid token if it
+// holds a malformed escape
+func decoded(tok *Token, buf []byte, n int, content []byte, unescape Unescaper) (*Token, []byte) {
+	value, err := unescape(content)
+	if err != nil {
+		return literal(tok, buf, Invalid, n, nil)
+	}
+	return literal(tok, buf, String, n, value)
+}
+
+// DoubleQuoted is a Tokenizer for double quoted strings with C and Go style escapes (see CUnescape)
+func DoubleQuoted(tok *Token, buf []byte) (*Token, []byte) {
+	return quoted(tok, buf, '"', CUnescape)
+}
+
+// SingleQuoted is a Tokenizer for single quoted strings with C and Go style escapes (see CUnescape)
+func SingleQuoted(tok *Token, buf []byte) (*Token, []byte) {
+	return quoted(tok, buf, '\'', CUnescape)
+}
+
+// QuotedStrings returns a Tokenizer for strings between quote characters, a backslash escapes
+// the following character and unescape decodes the content (e.g. QuotedStrings('"', JSONUnescape)).
+// The String token's Value is the raw text including quotes and Decoded the unescaped content.
+// A string that is unterminated, runs into a new line or holds a malformed escape is returned
+// as an Invalid token.
+func QuotedStrings(quote byte, unescape Unescaper) Tokenizer {
+	return func(tok *Token, buf []byte) (*Token, []byte) {
+		return quoted(tok, buf, quote, unescape)
+	}
+}
+
+// quoted implements QuotedStrings
+func quoted(tok *Token, buf []byte, quote byte, unescape Unescaper) (*Token, []byte) {
+	if len(tok.Value) != 1 || tok.Value[0] != quote {
+		return tok, buf
+	}
+	for i := 0; i < len(buf); i++ {
+		switch buf[i] {
+		case '\\':
+			i++
+		case '\n':
+			return literal(tok, buf, Invalid, i, nil)
+		case quote:
+			return decoded(tok, buf, i+1, buf[0:i], unescape)
+		}
+	}
+	return literal(tok, buf, Invalid, len(buf), nil)
+}
+
+// RawStrings is a Tokenizer for Go style backtick raw strings, Decoded is the content with
+// carriage returns removed as in Go
+func RawStrings(tok *Token, buf []byte) (*Token, []byte) {
+	if len(tok.Value) != 1 || tok.Value[0] != '`' {
+		return tok, buf
+	}
+	i := bytes.IndexByte(buf, '`')
+	if i < 0 {
+		return literal(tok, buf, Invalid, len(buf), nil)
+	}
+	return literal(tok, buf, RawString, i+1, bytes.ReplaceAll(buf[0:i], []byte("\r"), nil))
+}
+
+// TripleQuoted is a Tokenizer for Python style strings between three double or three single
+// quotes, which may span lines, escapes are decoded with CUnescape
+func TripleQuoted(tok *Token, buf []byte) (*Token, []byte) {
+	if len(tok.Value) != 1 || (tok.Value[0] != '"' && tok.Value[0] != '\'') {
+		return tok, buf
+	}
+	quote := tok.Value[0]
+	if len(buf) < 2 || buf[0] != quote || buf[1] != quote {
+		return tok, buf
+	}
+	for i := 2; i < len(buf); i++ {
+		switch {
+		case buf[i] == '\\':
+			i++
+		case buf[i] == quote && i+2 < len(buf) && buf[i+1] == quote && buf[i+2] == quote:
+			return decoded(tok, buf, i+3, buf[2:i], CUnescape)
+		}
+	}
+	return literal(tok, buf, Invalid, len(buf), nil)
+}
+
+// RustRawStrings is a Tokenizer for Rust style raw strings, r"..." or r#"..."# where the number
+// of hashes closing the string matches the opening, Decoded is the content
+func RustRawStrings(tok *Token, buf []byte) (*Token, []byte) {
+	if len(tok.Value) != 1 || tok.Value[0] != 'r' {
+		return tok, buf
+	}
+	hashes := 0
+	for hashes < len(buf) && buf[hashes] == '#' {
+		hashes++
+	}
+	if hashes >= len(buf) || buf[hashes] != '"' {
+		return tok, buf
+	}
+	closeValue := append([]byte{'"'}, buf[0:hashes]...)
+	start := hashes + 1
+	i := bytes.Index(buf[start:], closeValue)
+	if i < 0 {
+		return literal(tok, buf, Invalid, len(buf), nil)
+	}
+	return literal(tok, buf, RawString, start+i+len(closeValue), buf[start:start+i])
+}
+
+// LuaLongStrings is a Tokenizer for Lua long brackets, [[...]] or [==[...]==] where the level
+// of equal signs closing the string matches the opening. Decoded is the content without a new
+// line directly following the opening bracket, as in Lua.
+func LuaLongStrings(tok *Token, buf []byte) (*Token, []byte) {
+	if len(tok.Value) != 1 || tok.Value[0] != '[' {
+		return tok, buf
+	}
+	level := 0
+	for level < len(buf) && buf[level] == '=' {
+		level++
+	}
+	if level >= len(buf) || buf[level] != '[' {
+		return tok, buf
+	}
+	closeValue := append([]byte{']'}, buf[0:level]...)
+	closeValue = append(closeValue, ']')
+	start := level + 1
+	i := bytes.Index(buf[start:], closeValue)
+	if i < 0 {
+		return literal(tok, buf, Invalid, len(buf), nil)
+	}
+	content := buf[start : start+i]
+	if bytes.HasPrefix(content, []byte("\r\n")) {
+		content = content[2:]
+	} else if bytes.HasPrefix(content, []byte("\n")) {
+		content = content[1:]
+	}
+	return literal(tok, buf, RawString, start+i+len(closeValue), content)
+}
+
+// Heredocs is a Tokenizer for shell and Perl style here documents, <<EOF, << EOF, <<"EOF" or
+// <<'EOF' followed by lines ending with a line holding just the terminator, a quoted terminator
+// runs to the matching quote (e.g. <<'END-X'). With <<-EOF leading tabs are removed from the lines
+// and the terminator. Value holds the raw text from "<<" to the terminator, including the rest of
+// the line the heredoc starts on (e.g. "> out" in cat <<EOF > out), Decoded holds the body lines.
+func Heredocs(tok *Token, buf []byte) (*Token, []byte) {
+	if len(tok.Value) != 1 || tok.Value[0] != '<' || len(buf) == 0 || buf[0] != '<' {
+		return tok, buf
+	}
+	i := 1
+	dash := i < len(buf) && buf[i] == '-'
+	if dash == true {
+		i++
+	}
+	for i < len(buf) && (buf[i] == ' ' || buf[i] == '\t') {
+		i++
+	}
+	var terminator []byte
+	if i < len(buf) && (buf[i] == '"' || buf[i] == '\'') {
+		// A quoted terminator runs to the matching quote on the same line (e.g. <<'END-X')
+		quote := buf[i]
+		i++
+		end := bytes.IndexByte(buf[i:], quote)
+		if end <= 0 || bytes.IndexByte(buf[i:i+end], '\n') >= 0 {
+			return tok, buf
+		}
+		terminator = buf[i : i+end]
+		i += end + 1
+	} else {
+		start := i
+		for i < len(buf) && (buf[i] == '_' || isDigit(buf[i], 10) || (buf[i]|0x20 >= 'a' && buf[i]|0x20 <= 'z')) {
+			i++
+		}
+		terminator = buf[start:i]
+		if len(terminator) == 0 {
+			return tok, buf
+		}
+	}
+	// The body starts on the line following the heredoc marker
+	eol := bytes.IndexByte(buf[i:], '\n')
+	if eol < 0 {
+		return tok, buf
+	}
+	var body []byte
+	for i += eol + 1; i < len(buf); {
+		end := bytes.IndexByte(buf[i:], '\n')
+		if end < 0 {
+			end = len(buf) - i
+		}
+		line := buf[i : i+end]
+		if dash == true {
+			line = bytes.TrimLeft(line, "\t")
+		}
+		if bytes.Equal(bytes.TrimSuffix(line, []byte("\r")), terminator) {
+			if body == nil {
+				body = []byte{}
+			}
+			return literal(tok, buf, Heredoc, i+end, body)
+		}
+		body = append(body, line...)
+		if i+end < len(buf) {
+			body = append(body, '\n')
+		}
+		i += end + 1
+	}
+	return literal(tok, buf, Invalid, len(buf), nil)
+}
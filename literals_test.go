@@ -0,0 +1,88 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"testing"
+)
+
+func TestStringLiterals(t *testing.T) {
+	for _, test := range []struct {
+		Name    string
+		Fn      Tokenizer
+		Src     string
+		Type    string
+		Value   string
+		Decoded string
+		Rest    string
+	}{
+		{"double", DoubleQuoted, `"a\"b\n" x`, String, `"a\"b\n"`, "a\"b\n", " x"},
+		{"single", SingleQuoted, `'it\'s' x`, String, `'it\'s'`, "it's", " x"},
+		{"empty", DoubleQuoted, `"" x`, String, `""`, "", " x"},
+		{"unterminated", DoubleQuoted, "\"abc\nx", Invalid, "\"abc", "", "\nx"},
+		{"bad escape", DoubleQuoted, `"a\qb" x`, Invalid, `"a\qb"`, "", " x"},
+		{"json", QuotedStrings('"', JSONUnescape), `"😀"`, String, `"😀"`, "😀", ""},
+		{"raw", RawStrings, "`a\\n\r\nb` x", RawString, "`a\\n\r\nb`", "a\\n\nb", " x"},
+		{"triple", TripleQuoted, "\"\"\"say \"hi\"\n\\t\"\"\" x", String, "\"\"\"say \"hi\"\n\\t\"\"\"", "say \"hi\"\n\t", " x"},
+		{"triple single", TripleQuoted, "'''a''' x", String, "'''a'''", "a", " x"},
+		{"not triple", TripleQuoted, `"a"`, Punctuation, `"`, "", `a"`},
+		{"rust", RustRawStrings, `r#"say "hi""# x`, RawString, `r#"say "hi""#`, `say "hi"`, " x"},
+		{"rust plain", RustRawStrings, `r"\n" x`, RawString, `r"\n"`, `\n`, " x"},
+		{"rust not raw", RustRawStrings, `rust`, Letter, `r`, "", "ust"},
+		{"lua", LuaLongStrings, "[==[\na]]b]==] x", RawString, "[==[\na]]b]==]", "a]]b", " x"},
+		{"lua plain", LuaLongStrings, "[[x]]", RawString, "[[x]]", "x", ""},
+		{"lua index", LuaLongStrings, "[=x", Punctuation, "[", "", "=x"},
+		{"heredoc", Heredocs, "<<EOF > out\nline 1\nline 2\nEOF\nnext", Heredoc, "<<EOF > out\nline 1\nline 2\nEOF", "line 1\nline 2\n", "\nnext"},
+		{"heredoc quoted", Heredocs, "<<'END';\n$x\nEND", Heredoc, "<<'END';\n$x\nEND", "$x\n", ""},
+		{"heredoc dash", Heredocs, "<<-EOF\n\tindented\n\tEOF", Heredoc, "<<-EOF\n\tindented\n\tEOF", "indented\n", ""},
+		{"heredoc spaced", Heredocs, "<< EOF\nx\nEOF", Heredoc, "<< EOF\nx\nEOF", "x\n", ""},
+		{"heredoc dash spaced", Heredocs, "<<-\tEOF\n\tx\n\tEOF", Heredoc, "<<-\tEOF\n\tx\n\tEOF", "x\n", ""},
+		{"heredoc quoted dash", Heredocs, "<<'END-X'\nx\nEND-X", Heredoc, "<<'END-X'\nx\nEND-X", "x\n", ""},
+		{"heredoc quoted dot", Heredocs, "<< \"END.TXT\"\nx\nEND.TXT\n", Heredoc, "<< \"END.TXT\"\nx\nEND.TXT", "x\n", "\n"},
+		{"heredoc quote unclosed", Heredocs, "<<'END\nx\nEND'", Punctuation, "<", "", "<'END\nx\nEND'"},
+		{"heredoc empty", Heredocs, "<<EOF\nEOF", Heredoc, "<<EOF\nEOF", "", ""},
+		{"heredoc unterminated", Heredocs, "<<EOF\nabc\n", Invalid, "<<EOF\nabc\n", "", ""},
+		{"shift", Heredocs, "<<2", Punctuation, "<", "", "<2"},
+	} {
+		token, buf := Tok2([]byte(test.Src), test.Fn)
+		if token.Type != test.Type || string(token.Value) != test.Value || string(buf) != test.Rest {
+			t.Errorf("%s: expected %s %q then %q, found %s then %q", test.Name, test.Type, test.Value, test.Rest, token, buf)
+		}
+		if string(token.Decoded) != test.Decoded {
+			t.Errorf("%s: expected decoded %q, found %q", test.Name, test.Decoded, token.Decoded)
+		}
+		if (test.Type == String || test.Type == RawString || test.Type == Heredoc) && token.Decoded == nil {
+			t.Errorf("%s: expected Decoded to be set", test.Name)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"fmt"
+)
+
+// Mode is a named set of rules for a ModeLexer
+type Mode struct {
+	Name  string
+	Rules []Rule
+}
+
+// ModeLexer switches between sets of rules depending on context (e.g. inside or outside
+// of an HTML tag or string interpolation). It keeps a stack of modes, a matched Rule with
+// Pop set returns to the previous mode then a Rule with Push set enters the named mode.
+// Each mode is compiled into a DFA. A ModeLexer holds state so it is not safe for concurrent use.
+type ModeLexer struct {
+	modes map[string]*DFA
+	first string
+	stack []string
+}
+
+// NewModeLexer compiles modes into a ModeLexer, the first mode is the initial one
+func NewModeLexer(modes ...Mode) (*ModeLexer, error) {
+	if len(modes) == 0 {
+		return nil, fmt.Errorf("no modes")
+	}
+	ml := &ModeLexer{
+		modes: map[string]*DFA{},
+		first: modes[0].Name,
+	}
+	for _, mode := range modes {
+		if _, exists := ml.modes[mode.Name]; exists == true {
+			return nil, fmt.Errorf("mode %s is defined more than once", mode.Name)
+		}
+		d, err := CompileDFA(mode.Rules...)
+		if err != nil {
+			return nil, fmt.Errorf("mode %s, %s", mode.Name, err)
+		}
+		ml.modes[mode.Name] = d
+	}
+	for _, mode := range modes {
+		for _, rule := range mode.Rules {
+			if _, ok := ml.modes[rule.Push]; rule.Push != "" && ok == false {
+				return nil, fmt.Errorf("mode %s, rule %s pushes unknown mode %s", mode.Name, rule.Type, rule.Push)
+			}
+		}
+	}
+	ml.Reset()
+	return ml, nil
+}
+
+// Reset returns the ModeLexer to its initial mode
+func (ml *ModeLexer) Reset() {
+	ml.stack = []string{ml.first}
+}
+
+// Mode returns the name of the current mode
+func (ml *ModeLexer) Mode() string {
+	return ml.stack[len(ml.stack)-1]
+}
+
+// Push enters the named mode
+func (ml *ModeLexer) Push(name string) error {
+	if _, ok := ml.modes[name]; ok == false {
+		return fmt.Errorf("unknown mode %s", name)
+	}
+	ml.stack = append(ml.stack, name)
+	return nil
+}
+
+// Pop returns to the previous mode, the initial mode is never popped. It returns false if
+// there was no mode to pop.
+func (ml *ModeLexer) Pop() bool {
+	if len(ml.stack) <= 1 {
+		return false
+	}
+	ml.stack = ml.stack[:len(ml.stack)-1]
+	return true
+}
+
+// Stack returns a copy of the mode stack, the current mode is last
+func (ml *ModeLexer) Stack() []string {
+	return append([]string{}, ml.stack...)
+}
+
+// SetStack replaces the mode stack (e.g. with one saved from Stack())
+func (ml *ModeLexer) SetStack(stack []string) error {
+	if len(stack) == 0 {
+		return fmt.Errorf("empty mode stack")
+	}
+	for _, name := range stack {
+		if _, ok := ml.modes[name]; ok == false {
+			return fmt.Errorf("unknown mode %s", name)
+		}
+	}
+	ml.stack = append([]string{}, stack...)
+	return nil
+}
+
+// Tok returns the next token matched by the current mode's rules and the remaining []byte
+func (ml *ModeLexer) Tok(buf []byte) (*Token, []byte) {
+	return ml.TokAt(buf, StartPosition())
+}
+
+// TokAt is like Tok where buf starts at pos
+func (ml *ModeLexer) TokAt(buf []byte, pos Position) (*Token, []byte) {
+	for {
+		d := ml.modes[ml.Mode()]
+		rule, length := d.Match(buf)
+		if rule < 0 {
+			return TokAt(buf, pos)
+		}
+		r := d.Rules[rule]
+		value := buf[0:length]
+		if r.Pop == true {
+			ml.Pop()
+		}
+		if r.Push != "" {
+			ml.stack = append(ml.stack, r.Push)
+		}
+		if r.Skip == true {
+			pos = pos.Advance(value)
+			buf = buf[length:]
+			continue
+		}
+		return &Token{
+			Type:  r.Type,
+			Value: value,
+			Pos:   pos.orStart(),
+		}, buf[length:]
+	}
+}
+
+// Tokenizer re-scans tok and buf with the current mode's rules, it can be passed to Tok2() or Skip2()
+func (ml *ModeLexer) Tokenizer(tok *Token, buf []byte) (*Token, []byte) {
+	if tok.Type == EOF {
+		return tok, buf
+	}
+	return ml.TokAt(join(tok.Value, buf), tok.Pos)
+}
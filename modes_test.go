@@ -0,0 +1,111 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"testing"
+)
+
+func TestModeLexer(t *testing.T) {
+	// String interpolation, "${ ... }" switches to expression rules until the matching "}"
+	ml, err := NewModeLexer(
+		Mode{Name: "code", Rules: []Rule{
+			{Type: "Ident", Pattern: `[a-z]+`},
+			{Type: "Op", Pattern: `[-+=]`},
+			{Type: "OpenBrace", Pattern: `\{`, Push: "code"},
+			{Type: "CloseBrace", Pattern: `\}`, Pop: true},
+			{Type: "Quote", Pattern: `"`, Push: "string"},
+			{Type: Space, Pattern: `\s+`, Skip: true},
+		}},
+		Mode{Name: "string", Rules: []Rule{
+			{Type: "Text", Pattern: `([^"$\\]|\\.|\$[^{"])+`},
+			{Type: "Interpolate", Pattern: `\$\{`, Push: "code"},
+			{Type: "Quote", Pattern: `"`, Pop: true},
+		}},
+	)
+	if err != nil {
+		t.Errorf("NewModeLexer() failed, %s", err)
+		t.FailNow()
+	}
+	expected := []struct {
+		Type  string
+		Value string
+		Mode  string
+	}{
+		{"Ident", "s", "code"},
+		{"Op", "=", "code"},
+		{"Quote", `"`, "string"},
+		{"Text", "sum is ", "string"},
+		{"Interpolate", "${", "code"},
+		{"Ident", "a", "code"},
+		{"Op", "+", "code"},
+		{"Ident", "b", "code"},
+		{"CloseBrace", "}", "string"},
+		{"Text", "!", "string"},
+		{"Quote", `"`, "code"},
+		{EOF, "", "code"},
+	}
+	buf := []byte(`s = "sum is ${a + b}!"`)
+	var token *Token
+	for i, exp := range expected {
+		token, buf = ml.Tok(buf)
+		if token.Type != exp.Type || string(token.Value) != exp.Value || ml.Mode() != exp.Mode {
+			t.Errorf("%d: expected {%q: %q} in %s, found %s in %s", i, exp.Type, exp.Value, exp.Mode, token, ml.Mode())
+		}
+	}
+
+	// Modes carry across Tok2 calls when used as a Tokenizer
+	ml.Reset()
+	buf = []byte(`"x${y}"`)
+	types := []string{}
+	for {
+		token, buf = Tok2(buf, ml.Tokenizer)
+		if token.Type == EOF {
+			break
+		}
+		types = append(types, token.Type)
+	}
+	if len(types) != 6 || types[1] != "Text" || types[3] != "Ident" {
+		t.Errorf("unexpected token types %v", types)
+	}
+
+	if err := ml.Push("nowhere"); err == nil {
+		t.Errorf("expected an error pushing an unknown mode")
+	}
+	if ml.Pop() == true {
+		t.Errorf("expected Pop() to keep the initial mode")
+	}
+	if _, err := NewModeLexer(Mode{Name: "a", Rules: []Rule{{Type: "X", Pattern: "x", Push: "b"}}}); err == nil {
+		t.Errorf("expected an error for pushing an undefined mode")
+	}
+}
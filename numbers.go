@@ -0,0 +1,283 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+const (
+	// Integer is a decimal integer literal (e.g. 42, 1_000_000)
+	Integer = "Integer"
+	// Float is a decimal floating point literal (e.g. 3.14, -3.5e10, .5)
+	Float = "Float"
+	// Hex is a hexadecimal integer literal (e.g. 0x1F)
+	Hex = "Hex"
+	// Octal is an octal integer literal (e.g. 0o755 or 0755)
+	Octal = "Octal"
+	// Binary is a binary integer literal (e.g. 0b1010)
+	Binary = "Binary"
+)
+
+// numberPrefix is the token type and base of a number literal prefix (e.g. 0x)
+type numberPrefix struct {
+	tokenType string
+	base      int
+}
+
+// numberPrefixes map the letter following a leading zero to its numberPrefix
+var numberPrefixes = map[byte]numberPrefix{
+	'x': {Hex, 16}, 'X': {Hex, 16},
+	'o': {Octal, 8}, 'O': {Octal, 8},
+	'b': {Binary, 2}, 'B': {Binary, 2},
+}
+
+// isDigit reports if c is a digit in base
+func isDigit(c byte, base int) bool {
+	switch base {
+	case 2:
+		return c == '0' || c == '1'
+	case 8:
+		return c >= '0' && c <= '7'
+	case 16:
+		return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+	}
+	return c >= '0' && c <= '9'
+}
+
+// digits returns the length of the run of digits in base at the start of b, an underscore
+// is accepted as a separator when it is followed by a digit
+func digits(b []byte, base int) int {
+	n := 0
+	for n < len(b) {
+		switch {
+		case isDigit(b[n], base):
+			n++
+		case b[n] == '_' && n+1 < len(b) && isDigit(b[n+1], base):
+			n += 2
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// scanExponent returns the length of the exponent (e.g. e10, E-3) at the start of b or zero
+// if there isn't one
+func scanExponent(b []byte) int {
+	if len(b) == 0 || (b[0] != 'e' && b[0] != 'E') {
+		return 0
+	}
+	e := 1
+	if e < len(b) && (b[e] == '+' || b[e] == '-') {
+		e++
+	}
+	if m := digits(b[e:], 10); m > 0 {
+		return e + m
+	}
+	return 0
+}
+
+// scanFraction returns the length of the fraction and exponent of a float following its
+// period at the start of b (e.g. "5e3" of ".5e3") or zero if b doesn't start with a digit
+func scanFraction(b []byte) int {
+	if len(b) == 0 || isDigit(b[0], 10) == false {
+		return 0
+	}
+	n := digits(b, 10)
+	return n + scanExponent(b[n:])
+}
+
+// scanNumber returns the token type of the number literal starting with the digit c followed
+// by b and how many bytes of b it takes. A leading zero followed by a digit that isn't octal
+// (e.g. 09) is Invalid as in C and Go.
+func scanNumber(c byte, b []byte) (string, int) {
+	if c == '0' && len(b) > 0 {
+		if prefix, ok := numberPrefixes[b[0]]; ok == true {
+			// Go allows a separator straight after the prefix (e.g. 0x_1F)
+			start := 1
+			if start < len(b) && b[start] == '_' {
+				start++
+			}
+			n := digits(b[start:], prefix.base)
+			// Decimal digits past those of the base make the whole run invalid (e.g. 0b102, 0o78)
+			if run := digits(b[start:], 10); run > n {
+				return Invalid, start + run
+			}
+			if n > 0 {
+				return prefix.tokenType, start + n
+			}
+			return Integer, 0
+		}
+	}
+	tokenType := Integer
+	// c is a digit so b may continue the run with a digit or a separator
+	n := digits(b, 10)
+	if n+1 < len(b) && b[n] == '.' {
+		if m := scanFraction(b[n+1:]); m > 0 {
+			tokenType = Float
+			n += 1 + m
+		}
+	}
+	if m := scanExponent(b[n:]); m > 0 && tokenType == Integer {
+		tokenType = Float
+		n += m
+	}
+	// A leading zero followed by digits is an octal literal (e.g. 0755)
+	if tokenType == Integer && c == '0' && n > 0 {
+		if digits(b, 8) != n {
+			return Invalid, n
+		}
+		tokenType = Octal
+	}
+	return tokenType, n
+}
+
+// Numbers is a Tokenizer assembling numeric literals, it returns Integer, Float, Hex, Octal and
+// Binary tokens for a Numeral or a period followed by a digit. Underscores may separate digits
+// (e.g. 1_000_000), a period must be followed by a digit to be part of a Float. A decimal
+// integer with a leading zero that isn't octal (e.g. 09) is returned as an Invalid token.
+// Use SignedNumbers to include a leading sign.
+func Numbers(tok *Token, buf []byte) (*Token, []byte) {
+	return numbers(tok, buf, false)
+}
+
+// SignedNumbers is like Numbers but a "+" or "-" followed by a number is included in the token
+// (e.g. -3.5e10), so "a-1" becomes the tokens "a" and "-1"
+func SignedNumbers(tok *Token, buf []byte) (*Token, []byte) {
+	return numbers(tok, buf, true)
+}
+
+// numbers implements Numbers and SignedNumbers, the number is scanned in place so no copy
+// of buf is made
+func numbers(tok *Token, buf []byte, signed bool) (*Token, []byte) {
+	if len(tok.Value) != 1 {
+		return tok, buf
+	}
+	c := tok.Value[0]
+	var (
+		tokenType string
+		n         int
+	)
+	switch {
+	case isDigit(c, 10) == true:
+		tokenType, n = scanNumber(c, buf)
+	case c == '.':
+		tokenType, n = Float, scanFraction(buf)
+	case signed == true && (c == '-' || c == '+') && len(buf) > 0:
+		switch {
+		case buf[0] == '.':
+			if m := scanFraction(buf[1:]); m > 0 {
+				tokenType, n = Float, 1+m
+			}
+		case isDigit(buf[0], 10) == true:
+			tokenType, n = scanNumber(buf[0], buf[1:])
+			n++
+		}
+	}
+	if tokenType == "" || (n == 0 && c == '.') {
+		return tok, buf
+	}
+	return &Token{
+		Type:  tokenType,
+		Value: join(tok.Value, buf[0:n]),
+		Pos:   tok.Pos,
+	}, buf[n:]
+}
+
+// isNumberType reports if tokenType is one of the types returned by Numbers
+func isNumberType(tokenType string) bool {
+	switch tokenType {
+	case Integer, Float, Hex, Octal, Binary:
+		return true
+	}
+	return false
+}
+
+// Int64 returns the value of an Integer, Hex, Octal or Binary token. An error wrapping
+// strconv.ErrRange is returned if the value overflows an int64.
+func (t *Token) Int64() (int64, error) {
+	if isNumberType(t.Type) == false || t.Type == Float {
+		return 0, fmt.Errorf("%s token %q is not an integer", t.Type, t.Value)
+	}
+	return strconv.ParseInt(string(t.Value), 0, 64)
+}
+
+// Float64 returns the value of a number token. An error wrapping strconv.ErrRange is
+// returned if the value overflows a float64.
+func (t *Token) Float64() (float64, error) {
+	if t.Type == Float {
+		return strconv.ParseFloat(string(t.Value), 64)
+	}
+	i, err := t.BigInt()
+	if err != nil {
+		return 0, err
+	}
+	f, _ := new(big.Float).SetInt(i).Float64()
+	if math.IsInf(f, 0) {
+		return f, &strconv.NumError{Func: "Float64", Num: string(t.Value), Err: strconv.ErrRange}
+	}
+	return f, nil
+}
+
+// BigInt returns the value of an Integer, Hex, Octal or Binary token of any size
+func (t *Token) BigInt() (*big.Int, error) {
+	if isNumberType(t.Type) == false || t.Type == Float {
+		return nil, fmt.Errorf("%s token %q is not an integer", t.Type, t.Value)
+	}
+	i, ok := new(big.Int).SetString(string(t.Value), 0)
+	if ok == false {
+		return nil, fmt.Errorf("invalid %s %q", t.Type, t.Value)
+	}
+	return i, nil
+}
+
+// BigFloat returns the value of a number token, integers are exact and floats are
+// rounded to 64 bits of precision
+func (t *Token) BigFloat() (*big.Float, error) {
+	if t.Type != Float {
+		i, err := t.BigInt()
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Float).SetInt(i), nil
+	}
+	f, ok := new(big.Float).SetString(string(t.Value))
+	if ok == false {
+		return nil, fmt.Errorf("invalid %s %q", t.Type, t.Value)
+	}
+	return f, nil
+}
@@ -0,0 +1,165 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"errors"
+	"math/big"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestNumbers(t *testing.T) {
+	src := []byte("42 1_000_000 3.14 2.5e10 1E-3 .5 0x1F 0X_ff 0o17 0755 0b1010 7. 1__2 0xg 09 0789 09.5 0b102 0o78 0b2 0b1_2 ٣")
+	expected := []struct {
+		Type  string
+		Value string
+	}{
+		{Integer, "42"}, {Integer, "1_000_000"}, {Float, "3.14"}, {Float, "2.5e10"}, {Float, "1E-3"},
+		{Float, ".5"}, {Hex, "0x1F"}, {Hex, "0X_ff"}, {Octal, "0o17"}, {Octal, "0755"}, {Binary, "0b1010"},
+		{Integer, "7"}, {Punctuation, "."}, {Integer, "1"}, {Punctuation, "_"}, {Punctuation, "_"}, {Integer, "2"},
+		{Integer, "0"}, {Letter, "x"}, {Letter, "g"}, {Invalid, "09"}, {Invalid, "0789"}, {Float, "09.5"},
+		{Invalid, "0b102"}, {Invalid, "0o78"}, {Invalid, "0b2"}, {Invalid, "0b1_2"}, {Numeral, "٣"},
+	}
+	i := 0
+	for token := range Drop(AllWith(src, Numbers), Space) {
+		if i >= len(expected) {
+			t.Errorf("unexpected token %s", token)
+			break
+		}
+		if token.Type != expected[i].Type || string(token.Value) != expected[i].Value {
+			t.Errorf("(%d) expected %s %q, found %s", i, expected[i].Type, expected[i].Value, token)
+		}
+		i++
+	}
+	if i != len(expected) {
+		t.Errorf("expected %d tokens, found %d", len(expected), i)
+	}
+
+	token, buf := Tok2([]byte("-3.5e10+x"), SignedNumbers)
+	if token.Type != Float || string(token.Value) != "-3.5e10" || string(buf) != "+x" {
+		t.Errorf("expected Float -3.5e10, found %s then [%s]", token, buf)
+	}
+	token, _ = Tok2([]byte("+.5"), SignedNumbers)
+	if token.Type != Float || string(token.Value) != "+.5" {
+		t.Errorf("expected Float +.5, found %s", token)
+	}
+	token, _ = Tok2([]byte("-x"), SignedNumbers)
+	if token.Type != Punctuation || string(token.Value) != "-" {
+		t.Errorf("expected Punctuation -, found %s", token)
+	}
+	token, _ = Tok2([]byte("-1"), Numbers)
+	if token.Type != Punctuation {
+		t.Errorf("expected Numbers to leave the sign alone, found %s", token)
+	}
+
+	// Numbers are scanned in place, the rest of the input is never copied
+	src = []byte(strings.Repeat(".5 -.5e3 ", 10000))
+	for _, fn := range []Tokenizer{Numbers, SignedNumbers} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		for i := 0; i < 100; i++ {
+			fn(&Token{Type: Punctuation, Value: src[0:1]}, src[1:])
+			fn(&Token{Type: Punctuation, Value: src[3:4]}, src[4:])
+		}
+		runtime.ReadMemStats(&after)
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > uint64(len(src)) {
+			t.Errorf("expected only token allocations, found %d bytes allocated", allocated)
+		}
+	}
+}
+
+func TestNumberValues(t *testing.T) {
+	for value, expected := range map[string]int64{
+		"42": 42, "1_000": 1000, "0x1F": 31, "0o17": 15, "0755": 493, "0b1010": 10, "-0x10": -16,
+	} {
+		token, _ := Tok2([]byte(value), SignedNumbers)
+		i, err := token.Int64()
+		if err != nil || i != expected {
+			t.Errorf("expected %s to be %d, found %d %v", value, expected, i, err)
+		}
+		f, err := token.Float64()
+		if err != nil || f != float64(expected) {
+			t.Errorf("expected %s to be %d as a float, found %v %v", value, expected, f, err)
+		}
+	}
+
+	token, _ := Tok2([]byte("-3.5e10"), SignedNumbers)
+	if f, err := token.Float64(); err != nil || f != -3.5e10 {
+		t.Errorf("expected -3.5e10, found %v %v", f, err)
+	}
+	if _, err := token.Int64(); err == nil {
+		t.Errorf("expected an error converting a Float to int64")
+	}
+
+	huge := "1" + strings.Repeat("0", 30)
+	token, _ = Tok2([]byte(huge), Numbers)
+	if _, err := token.Int64(); errors.Is(err, strconv.ErrRange) == false {
+		t.Errorf("expected strconv.ErrRange, found %v", err)
+	}
+	i, err := token.BigInt()
+	if expected, _ := new(big.Int).SetString(huge, 10); err != nil || i.Cmp(expected) != 0 {
+		t.Errorf("expected %s, found %s %v", huge, i, err)
+	}
+	f, err := token.BigFloat()
+	if err != nil || f.String() != "1e+30" {
+		t.Errorf("expected 1e+30, found %s %v", f, err)
+	}
+
+	token, _ = Tok2([]byte("1e400"), Numbers)
+	if _, err := token.Float64(); errors.Is(err, strconv.ErrRange) == false {
+		t.Errorf("expected strconv.ErrRange, found %v", err)
+	}
+	if f, err := token.BigFloat(); err != nil || f.IsInf() == true {
+		t.Errorf("expected a big.Float for 1e400, found %s %v", f, err)
+	}
+	token, _ = Tok2([]byte("0x"+strings.Repeat("f", 300)), Numbers)
+	if _, err := token.Float64(); errors.Is(err, strconv.ErrRange) == false {
+		t.Errorf("expected strconv.ErrRange, found %v", err)
+	}
+	for _, value := range []string{"09", "0789"} {
+		token, _ = Tok2([]byte(value), Numbers)
+		if _, err := token.Int64(); err == nil {
+			t.Errorf("expected %s not to convert", value)
+		}
+	}
+	token, _ = Tok2([]byte("09.5"), Numbers)
+	if f, err := token.Float64(); err != nil || f != 9.5 {
+		t.Errorf("expected 9.5, found %v %v", f, err)
+	}
+	if _, err := (&Token{Type: Word, Value: []byte("abc")}).BigInt(); err == nil {
+		t.Errorf("expected an error converting a Word")
+	}
+}
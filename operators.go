@@ -0,0 +1,162 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"sort"
+)
+
+// operatorNode is a node of the Operators trie, tokenType is set when the path to it is an operator
+type operatorNode struct {
+	children  map[byte]*operatorNode
+	tokenType string
+}
+
+// Operators is a trie of multi-byte operators (e.g. "==", "->", ">>=") each with its own token
+// type. Its Tokenizer greedily extends a Punctuation token to the longest registered operator.
+// Add operators before use, after that it is safe for concurrent use.
+type Operators struct {
+	root  *operatorNode
+	count int
+}
+
+// NewOperators returns an empty operator table
+func NewOperators() *Operators {
+	return &Operators{
+		root: &operatorNode{},
+	}
+}
+
+// Add registers values as operators of tokenType (e.g. Add("Arrow", []byte("->"))), adding
+// a value again replaces its type
+func (o *Operators) Add(tokenType string, values ...[]byte) {
+	for _, value := range values {
+		if len(value) == 0 {
+			continue
+		}
+		node := o.root
+		for _, c := range value {
+			next, ok := node.children[c]
+			if ok == false {
+				if node.children == nil {
+					node.children = map[byte]*operatorNode{}
+				}
+				next = &operatorNode{}
+				node.children[c] = next
+			}
+			node = next
+		}
+		if node.tokenType == "" {
+			o.count++
+		}
+		node.tokenType = tokenType
+	}
+}
+
+// AddMap registers each value of m as an operator of its key's type, keys are added in sorted order
+func (o *Operators) AddMap(m map[string][][]byte) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		o.Add(k, m[k]...)
+	}
+}
+
+// Len returns the number of operators registered
+func (o *Operators) Len() int {
+	return o.count
+}
+
+// Match returns the type and length of the longest operator at the start of buf, ok is false
+// if none matches
+func (o *Operators) Match(buf []byte) (string, int, bool) {
+	return o.match(nil, buf)
+}
+
+// match returns the longest operator starting with value followed by buf, the length counts
+// the bytes of value and buf
+func (o *Operators) match(value []byte, buf []byte) (string, int, bool) {
+	var (
+		tokenType string
+		n         int
+	)
+	node := o.root
+	for i := 0; i < len(value)+len(buf); i++ {
+		var c byte
+		if i < len(value) {
+			c = value[i]
+		} else {
+			c = buf[i-len(value)]
+		}
+		next, ok := node.children[c]
+		if ok == false {
+			break
+		}
+		node = next
+		if node.tokenType != "" {
+			tokenType, n = node.tokenType, i+1
+		}
+	}
+	return tokenType, n, n > 0
+}
+
+// Lookup returns the type of the operator equal to value
+func (o *Operators) Lookup(value []byte) (string, bool) {
+	tokenType, n, ok := o.Match(value)
+	if ok == false || n != len(value) {
+		return "", false
+	}
+	return tokenType, true
+}
+
+// Tokenizer extends a Punctuation or Symbol token to the longest operator starting with it,
+// consuming the extra bytes from buf. Tokens that don't start an operator are returned as is.
+// It can be passed to Tok2().
+func (o *Operators) Tokenizer(tok *Token, buf []byte) (*Token, []byte) {
+	if tok.Type != Punctuation && tok.Type != Symbol {
+		return tok, buf
+	}
+	tokenType, n, ok := o.match(tok.Value, buf)
+	if ok == false || n < len(tok.Value) {
+		return tok, buf
+	}
+	extra := n - len(tok.Value)
+	return &Token{
+		Type:  tokenType,
+		Value: join(tok.Value, buf[0:extra]),
+		Pos:   tok.Pos,
+	}, buf[extra:]
+}
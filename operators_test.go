@@ -0,0 +1,91 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"testing"
+)
+
+func TestOperators(t *testing.T) {
+	ops := NewOperators()
+	ops.Add("Equal", []byte("=="))
+	ops.Add("NotEqual", []byte("!="))
+	ops.Add("LessEqual", []byte("<="))
+	ops.Add("Arrow", []byte("->"))
+	ops.Add("Define", []byte(":="))
+	ops.Add("Ellipsis", []byte("..."))
+	ops.Add("Shift", []byte(">>"), []byte("<<"))
+	ops.AddMap(map[string][][]byte{
+		"ShiftAssign": {[]byte(">>="), []byte("<<=")},
+		"Assign":      {[]byte("=")},
+	})
+	ops.Add("Equal", []byte("=="))
+	if ops.Len() != 11 {
+		t.Errorf("expected 11 operators, found %d", ops.Len())
+	}
+
+	src := []byte("a==b != c<=d -> x := y... >>= >> > .. = ≠")
+	expected := []struct {
+		Type  string
+		Value string
+	}{
+		{Letter, "a"}, {"Equal", "=="}, {Letter, "b"}, {"NotEqual", "!="}, {Letter, "c"}, {"LessEqual", "<="},
+		{Letter, "d"}, {"Arrow", "->"}, {Letter, "x"}, {"Define", ":="}, {Letter, "y"}, {"Ellipsis", "..."},
+		{"ShiftAssign", ">>="}, {"Shift", ">>"}, {Punctuation, ">"}, {Punctuation, "."}, {Punctuation, "."},
+		{"Assign", "="}, {Symbol, "≠"},
+	}
+	i := 0
+	for token := range Drop(AllWith(src, ops.Tokenizer), Space) {
+		if i >= len(expected) {
+			t.Errorf("unexpected token %s", token)
+			break
+		}
+		if token.Type != expected[i].Type || string(token.Value) != expected[i].Value {
+			t.Errorf("(%d) expected %s %q, found %s", i, expected[i].Type, expected[i].Value, token)
+		}
+		i++
+	}
+	if i != len(expected) {
+		t.Errorf("expected %d tokens, found %d", len(expected), i)
+	}
+
+	if tokenType, n, ok := ops.Match([]byte(">>=x")); ok == false || tokenType != "ShiftAssign" || n != 3 {
+		t.Errorf("expected ShiftAssign of 3 bytes, found %s %d %t", tokenType, n, ok)
+	}
+	if tokenType, ok := ops.Lookup([]byte("->")); ok == false || tokenType != "Arrow" {
+		t.Errorf("expected Arrow, found %s %t", tokenType, ok)
+	}
+	if _, ok := ops.Lookup([]byte("-")); ok == true {
+		t.Errorf("expected - not to be an operator")
+	}
+}
@@ -0,0 +1,108 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"sync"
+	"unicode/utf8"
+)
+
+// TokInto is like TokAt but fills the caller's token rather than allocating a new one,
+// token.Value slices buf without copying. Reusing one Token for a whole buffer tokenizes
+// without allocating.
+func TokInto(token *Token, buf []byte, pos Position) []byte {
+	return defaultLexer.TokInto(token, buf, pos)
+}
+
+// Tok2Into is like Tok2At but fills the caller's token, fn is handed token. A Tokenizer
+// that updates and returns the token it is given (e.g. Words) does not allocate.
+func Tok2Into(token *Token, buf []byte, fn Tokenizer, pos Position) []byte {
+	return defaultLexer.Tok2Into(token, buf, fn, pos)
+}
+
+// TokInto is like the package level TokInto() using the Lexer's character classes
+func (l *Lexer) TokInto(token *Token, buf []byte, pos Position) []byte {
+	pos = pos.orStart()
+	if len(buf) == 0 {
+		token.Type, token.Value, token.Pos, token.Decoded = EOF, buf[0:0], pos, nil
+		return nil
+	}
+	_, size := utf8.DecodeRune(buf)
+	token.Type, token.Value, token.Pos, token.Decoded = l.tokenType(buf[0:size]), buf[0:size], pos, nil
+	return buf[size:]
+}
+
+// Tok2Into is like the package level Tok2Into() using the Lexer's character classes
+func (l *Lexer) Tok2Into(token *Token, buf []byte, fn Tokenizer, pos Position) []byte {
+	rest := l.TokInto(token, buf, pos)
+	result, rest := fn(token, rest)
+	if result != token {
+		*token = *result
+	}
+	return rest
+}
+
+// TokenPool recycles Tokens for callers that retain many tokens for a short while,
+// e.g. a parser holding the tokens of one statement. It is safe for concurrent use.
+type TokenPool struct {
+	pool sync.Pool
+}
+
+// NewTokenPool returns an empty TokenPool
+func NewTokenPool() *TokenPool {
+	return &TokenPool{
+		pool: sync.Pool{
+			New: func() any {
+				return new(Token)
+			},
+		},
+	}
+}
+
+// Get returns a Token from the pool, fill it with TokInto() or Tok2Into()
+func (p *TokenPool) Get() *Token {
+	return p.pool.Get().(*Token)
+}
+
+// Put returns token to the pool, token must not be used afterwards. The token is
+// cleared so the pool does not keep its input buffer alive.
+func (p *TokenPool) Put(token *Token) {
+	*token = Token{}
+	p.pool.Put(token)
+}
+
+// Tok is like TokAt using a Token from the pool, Put() it back once done with it
+func (p *TokenPool) Tok(buf []byte, pos Position) (*Token, []byte) {
+	token := p.Get()
+	return token, TokInto(token, buf, pos)
+}
@@ -0,0 +1,162 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTokInto(t *testing.T) {
+	src := []byte("café 東京, 42")
+	var (
+		token Token
+		found []*Token
+	)
+	buf, pos := src, StartPosition()
+	for {
+		buf = TokInto(&token, buf, pos)
+		if token.Type == EOF {
+			break
+		}
+		copied := token
+		found = append(found, &copied)
+		pos = token.End()
+	}
+	i := 0
+	for expected := range All(src) {
+		if i >= len(found) {
+			t.Errorf("expected %s, found nothing", expected)
+			break
+		}
+		if found[i].Type != expected.Type || bytes.Equal(found[i].Value, expected.Value) == false || found[i].Pos != expected.Pos {
+			t.Errorf("(%d) expected %s at %s, found %s at %s", i, expected, expected.Pos, found[i], found[i].Pos)
+		}
+		i++
+	}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		buf, pos := src, StartPosition()
+		for len(buf) > 0 {
+			buf = Tok2Into(&token, buf, Words, pos)
+			pos = token.End()
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, found %v", allocs)
+	}
+	buf = Tok2Into(&token, []byte("naïve words"), Words, StartPosition())
+	if token.Type != Word || string(token.Value) != "naïve" || string(buf) != " words" {
+		t.Errorf("expected Word naïve, found %s then [%s]", &token, buf)
+	}
+}
+
+func TestTokenPool(t *testing.T) {
+	pool := NewTokenPool()
+	token, buf := pool.Tok([]byte("ab"), StartPosition())
+	if string(token.Value) != "a" || string(buf) != "b" {
+		t.Errorf("expected a then b, found %s then [%s]", token, buf)
+	}
+	pool.Put(token)
+	token = pool.Get()
+	if token.Type != "" || token.Value != nil {
+		t.Errorf("expected a cleared token, found %s", token)
+	}
+}
+
+func BenchmarkTok(b *testing.B) {
+	src := benchmarkSource(b)
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		pos := StartPosition()
+		for buf := src; len(buf) > 0; {
+			var token *Token
+			token, buf = TokAt(buf, pos)
+			pos = token.End()
+		}
+	}
+	b.ReportMetric(testing.AllocsPerRun(1, func() { Tok(src) }), "allocs/token")
+}
+
+func BenchmarkTokInto(b *testing.B) {
+	src := benchmarkSource(b)
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	var token Token
+	for i := 0; i < b.N; i++ {
+		pos := StartPosition()
+		for buf := src; len(buf) > 0; {
+			buf = TokInto(&token, buf, pos)
+			pos = token.End()
+		}
+	}
+	b.ReportMetric(testing.AllocsPerRun(1, func() { TokInto(&token, src, StartPosition()) }), "allocs/token")
+}
+
+func BenchmarkTok2IntoWords(b *testing.B) {
+	src := benchmarkSource(b)
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	var token Token
+	for i := 0; i < b.N; i++ {
+		pos := StartPosition()
+		for buf := src; len(buf) > 0; {
+			buf = Tok2Into(&token, buf, Words, pos)
+			pos = token.End()
+		}
+	}
+}
+
+func BenchmarkTokenPool(b *testing.B) {
+	src := benchmarkSource(b)
+	b.SetBytes(int64(len(src)))
+	b.ReportAllocs()
+	pool := NewTokenPool()
+	held := make([]*Token, 0, 64)
+	for i := 0; i < b.N; i++ {
+		pos := StartPosition()
+		for buf := src; len(buf) > 0; {
+			var token *Token
+			token, buf = pool.Tok(buf, pos)
+			pos = token.End()
+			held = append(held, token)
+			if len(held) == cap(held) {
+				for _, token := range held {
+					pool.Put(token)
+				}
+				held = held[:0]
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Position describes where a token starts in the input. Offset is a byte offset,
+// Line is 1-based, Column counts UTF-8 code points (runes) and ByteColumn counts bytes,
+// both 1-based. A zero Position is treated as the start of the input.
+type Position struct {
+	Offset     int `xml:"offset" json:"offset"`
+	Line       int `xml:"line" json:"line"`
+	Column     int `xml:"column" json:"column"`
+	ByteColumn int `xml:"byte_column" json:"byte_column"`
+}
+
+// StartPosition returns the Position of the first byte of an input (offset 0, line 1, column 1)
+func StartPosition() Position {
+	return Position{Offset: 0, Line: 1, Column: 1, ByteColumn: 1}
+}
+
+// IsValid reports if the position has been set, a zero Position is not valid
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// orStart returns p or StartPosition() if p is the zero Position
+func (p Position) orStart() Position {
+	if p.IsValid() == false {
+		return StartPosition()
+	}
+	return p
+}
+
+// Advance returns the position found after consuming b. A new line ("\n") moves
+// to the start of the next line, a "\r\n" pair counts as a single line break.
+func (p Position) Advance(b []byte) Position {
+	p = p.orStart()
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		p.Offset += size
+		if r == '\n' {
+			p.Line++
+			p.Column = 1
+			p.ByteColumn = 1
+		} else {
+			p.Column++
+			p.ByteColumn += size
+		}
+		b = b[size:]
+	}
+	return p
+}
+
+// String returns a human readable position as line:column
+func (p Position) String() string {
+	if p.IsValid() == false {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// End returns the position immediately following the token's value
+func (t *Token) End() Position {
+	return t.Pos.Advance(t.Value)
+}
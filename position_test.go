@@ -0,0 +1,99 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPosition(t *testing.T) {
+	buf := []byte("a é\r\nb\n{x")
+	expected := []Position{
+		{Offset: 0, Line: 1, Column: 1, ByteColumn: 1},
+		{Offset: 1, Line: 1, Column: 2, ByteColumn: 2},
+		{Offset: 2, Line: 1, Column: 3, ByteColumn: 3},
+		{Offset: 4, Line: 1, Column: 4, ByteColumn: 5},
+		{Offset: 5, Line: 1, Column: 5, ByteColumn: 6},
+		{Offset: 6, Line: 2, Column: 1, ByteColumn: 1},
+		{Offset: 7, Line: 2, Column: 2, ByteColumn: 2},
+		{Offset: 8, Line: 3, Column: 1, ByteColumn: 1},
+		{Offset: 9, Line: 3, Column: 2, ByteColumn: 2},
+		{Offset: 10, Line: 3, Column: 3, ByteColumn: 3},
+	}
+	var (
+		token *Token
+		pos   Position
+	)
+	rest := buf
+	for i, exp := range expected {
+		peeked := PeekAt(rest, pos)
+		token, rest = TokAt(rest, pos)
+		if token.Pos != exp {
+			t.Errorf("%d: expected %+v, found %+v for %s", i, exp, token.Pos, token)
+		}
+		if peeked.Pos != token.Pos {
+			t.Errorf("%d: PeekAt() %+v != TokAt() %+v", i, peeked.Pos, token.Pos)
+		}
+		pos = token.End()
+	}
+	if token.Type != EOF {
+		t.Errorf("expected EOF, found %s", token)
+	}
+
+	// Skip and Skip2 account for the skipped content
+	_, token, _ = Skip(Space, []byte("  \n  x"))
+	if token.Pos.Line != 2 || token.Pos.Column != 3 || token.Pos.Offset != 5 {
+		t.Errorf("Skip() expected x at 2:3 offset 5, found %+v", token.Pos)
+	}
+	_, token, rest = Skip2(Space, []byte("one two"), Words)
+	_, token, rest = Skip2At(Space, rest, Words, token.End())
+	if string(token.Value) != "two" || token.Pos.Column != 5 {
+		t.Errorf("Skip2At() expected two at column 5, found %s at %+v", token, token.Pos)
+	}
+
+	// Backup carries the token's position
+	token, rest = TokAt([]byte("xy"), Position{Offset: 10, Line: 2, Column: 3, ByteColumn: 3})
+	rest, pos = BackupAt(token, rest)
+	if string(rest) != "xy" || pos.Offset != 10 || pos.Line != 2 {
+		t.Errorf("BackupAt() expected [xy] at offset 10, found [%s] at %+v", rest, pos)
+	}
+
+	// Between reports where the unclosed delimiter was opened
+	_, _, err := Between([]byte("{"), []byte("}"), []byte(""), []byte("a\n  {b {c}"))
+	if err == nil {
+		t.Errorf("expected missing closing error")
+	} else if strings.Contains(err.Error(), "2:3") == false {
+		t.Errorf("expected error to report 2:3, %s", err)
+	}
+}
@@ -0,0 +1,149 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"bytes"
+	"errors"
+)
+
+// Region is a delimited region of a buffer found by FindAllBetween(). Start and End are the
+// byte offsets of the region including its delimiters, ContentStart and ContentEnd those of
+// the content between them. Regions nested inside the content are its Children.
+type Region struct {
+	Start        int
+	End          int
+	ContentStart int
+	ContentEnd   int
+	// Pos is the position of the opening delimiter
+	Pos Position
+	// Value is the content between the delimiters, it shares the buffer's array
+	Value []byte
+	// Decoded is Value decoded by BetweenOptions.Unescape when it is set
+	Decoded []byte
+	// Depth is 0 for a top level region, 1 for its children and so on
+	Depth    int
+	Children []*Region
+}
+
+// FindAllBetween returns every top level region between openValue and closeValue in buf,
+// nested regions are returned as their Children
+func FindAllBetween(openValue []byte, closeValue []byte, escapeValue []byte, buf []byte) ([]*Region, error) {
+	return FindAllBetweenWith(openValue, closeValue, escapeValue, buf, BetweenOptions{})
+}
+
+// FindAllBetweenWith is like FindAllBetween adjusted by opts (see BetweenWith()), Recover and
+// Inclusive are ignored. Spans are always those of the raw bytes, with Unescape set each
+// region's Decoded holds its decoded Value. Regions found before an error are returned along with it.
+func FindAllBetweenWith(openValue []byte, closeValue []byte, escapeValue []byte, buf []byte, opts BetweenOptions) ([]*Region, error) {
+	return findAllBetween(openValue, closeValue, escapeValue, buf, 0, 0, opts)
+}
+
+// findAllBetween finds the regions of buf where buf starts at offset in the original buffer
+func findAllBetween(openValue []byte, closeValue []byte, escapeValue []byte, buf []byte, offset int, depth int, opts BetweenOptions) ([]*Region, error) {
+	var (
+		regions     []*Region
+		missingOpen *MissingOpenError
+	)
+	unescape := opts.Unescape
+	opts.Inclusive, opts.Recover, opts.Unescape = true, false, nil
+	pos := opts.Pos.orStart()
+	rest := buf
+	for len(rest) > 0 {
+		opts.Pos = pos
+		region, remaining, err := defaultLexer.BetweenWith(openValue, closeValue, escapeValue, rest, opts)
+		if errors.As(err, &missingOpen) == true {
+			break
+		}
+		if err != nil {
+			return regions, err
+		}
+		consumed := len(rest) - len(remaining)
+		start := consumed - len(region)
+		r := &Region{
+			Start:        offset + start,
+			End:          offset + consumed,
+			ContentStart: offset + start + len(openValue),
+			ContentEnd:   offset + consumed - len(closeValue),
+			Pos:          pos.Advance(rest[0:start]),
+			Value:        rest[start+len(openValue) : consumed-len(closeValue)],
+			Depth:        depth,
+		}
+		if unescape != nil {
+			r.Decoded, err = unescape(r.Value)
+			if err != nil {
+				var escapeErr *EscapeError
+				if errors.As(err, &escapeErr) == true {
+					escapeErr.Pos = r.Pos.Advance(openValue).Advance(r.Value[0:escapeErr.Offset])
+				}
+				return regions, err
+			}
+		}
+		if bytes.Equal(openValue, closeValue) == false && opts.Flat == false {
+			childOpts := opts
+			childOpts.Unescape = unescape
+			childOpts.Pos = r.Pos.Advance(openValue)
+			r.Children, err = findAllBetween(openValue, closeValue, escapeValue, r.Value, r.ContentStart, depth+1, childOpts)
+			if err != nil {
+				return regions, err
+			}
+		}
+		regions = append(regions, r)
+		pos = pos.Advance(rest[0:consumed])
+		offset += consumed
+		rest = remaining
+	}
+	return regions, nil
+}
+
+// ReplaceBetween returns a copy of buf where each top level region between openValue and closeValue,
+// delimiters included, is replaced by the result of fn. buf is not modified.
+func ReplaceBetween(openValue []byte, closeValue []byte, escapeValue []byte, buf []byte, fn func(*Region) []byte) ([]byte, error) {
+	return ReplaceBetweenWith(openValue, closeValue, escapeValue, buf, BetweenOptions{}, fn)
+}
+
+// ReplaceBetweenWith is like ReplaceBetween adjusted by opts (see FindAllBetweenWith())
+func ReplaceBetweenWith(openValue []byte, closeValue []byte, escapeValue []byte, buf []byte, opts BetweenOptions, fn func(*Region) []byte) ([]byte, error) {
+	regions, err := FindAllBetweenWith(openValue, closeValue, escapeValue, buf, opts)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(buf))
+	last := 0
+	for _, r := range regions {
+		out = append(out, buf[last:r.Start]...)
+		out = append(out, fn(r)...)
+		last = r.End
+	}
+	return append(out, buf[last:]...), nil
+}
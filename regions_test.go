@@ -0,0 +1,146 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestFindAllBetween(t *testing.T) {
+	buf := []byte("a {b {c} {d {e}}} f\n{g}")
+	regions, err := FindAllBetween([]byte("{"), []byte("}"), nil, buf)
+	if err != nil {
+		t.Errorf("FindAllBetween() failed, %s", err)
+		t.FailNow()
+	}
+	if len(regions) != 2 {
+		t.Errorf("expected 2 top level regions, found %d", len(regions))
+		t.FailNow()
+	}
+	r := regions[0]
+	if r.Start != 2 || r.End != 17 || string(buf[r.Start:r.End]) != "{b {c} {d {e}}}" || string(r.Value) != "b {c} {d {e}}" {
+		t.Errorf("unexpected first region %+v", r)
+	}
+	if string(buf[r.ContentStart:r.ContentEnd]) != string(r.Value) {
+		t.Errorf("content span [%d:%d] doesn't match value [%s]", r.ContentStart, r.ContentEnd, r.Value)
+	}
+	if len(r.Children) != 2 || string(r.Children[0].Value) != "c" || string(r.Children[1].Value) != "d {e}" {
+		t.Errorf("unexpected children %+v", r.Children)
+		t.FailNow()
+	}
+	e := r.Children[1].Children[0]
+	if string(e.Value) != "e" || e.Depth != 2 || e.Start != 12 || e.Pos.Column != 13 || string(buf[e.Start:e.End]) != "{e}" {
+		t.Errorf("unexpected grandchild %+v", e)
+	}
+	if g := regions[1]; string(g.Value) != "g" || g.Pos.Line != 2 || g.Pos.Column != 1 || g.Start != 20 {
+		t.Errorf("unexpected second region %+v", g)
+	}
+
+	_, err = FindAllBetween([]byte("{"), []byte("}"), nil, []byte("{a} {b"))
+	if err == nil {
+		t.Errorf("expected an error for an unterminated region")
+	}
+
+	regions, err = FindAllBetween([]byte("{"), []byte("}"), nil, []byte("none here"))
+	if err != nil || len(regions) != 0 {
+		t.Errorf("expected no regions, found %d, %v", len(regions), err)
+	}
+}
+
+func TestReplaceBetween(t *testing.T) {
+	buf := []byte("Hello {{name}}, you are {{age}} years old.")
+	values := map[string]string{"name": "Ada", "age": "36"}
+	original := append([]byte{}, buf...)
+	out, err := ReplaceBetween([]byte("{{"), []byte("}}"), nil, buf, func(r *Region) []byte {
+		return []byte(values[string(r.Value)])
+	})
+	if err != nil {
+		t.Errorf("ReplaceBetween() failed, %s", err)
+	}
+	if string(out) != "Hello Ada, you are 36 years old." {
+		t.Errorf("unexpected result [%s]", out)
+	}
+	if bytes.Equal(buf, original) == false {
+		t.Errorf("ReplaceBetween() modified its input")
+	}
+
+	// Children let the callback expand nested regions
+	var expand func(r *Region, buf []byte) []byte
+	expand = func(r *Region, buf []byte) []byte {
+		out := []byte{}
+		last := r.ContentStart
+		for _, child := range r.Children {
+			out = append(out, buf[last:child.Start]...)
+			out = append(out, expand(child, buf)...)
+			last = child.End
+		}
+		out = append(out, buf[last:r.ContentEnd]...)
+		return bytes.ToUpper(out)
+	}
+	buf = []byte("x (a (b) c) y")
+	out, _ = ReplaceBetween([]byte("("), []byte(")"), nil, buf, func(r *Region) []byte {
+		return expand(r, buf)
+	})
+	if string(out) != "x A B C y" {
+		t.Errorf("unexpected result [%s]", out)
+	}
+}
+
+func TestFindAllBetweenUnescape(t *testing.T) {
+	buf := []byte(`x {a\nb} y {c}`)
+	regions, err := FindAllBetweenWith([]byte("{"), []byte("}"), []byte(`\`), buf, BetweenOptions{Unescape: CUnescape})
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if len(regions) != 2 {
+		t.Fatalf("expected 2 regions, found %d", len(regions))
+	}
+	r := regions[0]
+	if r.Start != 2 || r.End != 8 || string(buf[r.Start:r.End]) != `{a\nb}` {
+		t.Errorf("expected span 2:8 [{a\\nb}], found %d:%d [%s]", r.Start, r.End, buf[r.Start:r.End])
+	}
+	if string(r.Value) != `a\nb` || string(r.Decoded) != "a\nb" {
+		t.Errorf("expected raw [a\\nb] decoded %q, found [%s] %q", "a\nb", r.Value, r.Decoded)
+	}
+	if r = regions[1]; r.Start != 11 || string(r.Decoded) != "c" {
+		t.Errorf("expected {c} at 11, found %d %q", r.Start, r.Decoded)
+	}
+
+	_, err = FindAllBetweenWith([]byte("{"), []byte("}"), []byte(`\`), []byte(`{ok} {b\q}`), BetweenOptions{Unescape: CUnescape})
+	var escapeErr *EscapeError
+	if errors.As(err, &escapeErr) == false || escapeErr.Pos.Offset != 7 {
+		t.Errorf("expected an *EscapeError at offset 7, found %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Rule pairs a token type with a regular expression (RE2 syntax, see regexp/syntax).
+// Tokens matched by a Rule with Skip set are consumed but not returned (e.g. white space).
+// Push and Pop change the mode of a ModeLexer after the token is matched, they are
+// ignored by RuleLexer and DFA.
+type Rule struct {
+	Type    string
+	Pattern string
+	Skip    bool
+	Push    string
+	Pop     bool
+}
+
+// RuleLexer tokenizes with an ordered list of Rules compiled into a single regular expression.
+// At each position the longest match wins, when rules match the same length the earlier
+// rule wins. Input no rule matches is returned one code point at a time as by Tok().
+type RuleLexer struct {
+	rules []Rule
+	re    *regexp.Regexp
+	// groups holds the index of the capture group wrapping each rule's pattern
+	groups []int
+}
+
+// NewRuleLexer compiles rules into a RuleLexer, it returns an error if a pattern is
+// invalid or matches the empty string
+func NewRuleLexer(rules ...Rule) (*RuleLexer, error) {
+	var (
+		alternates []string
+		groups     []int
+	)
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no rules")
+	}
+	group := 1
+	for i, rule := range rules {
+		re, err := checkRule(i, rule)
+		if err != nil {
+			return nil, err
+		}
+		alternates = append(alternates, `(`+rule.Pattern+`)`)
+		groups = append(groups, group)
+		group += re.NumSubexp() + 1
+	}
+	re, err := regexp.Compile(`^(?:` + strings.Join(alternates, `|`) + `)`)
+	if err != nil {
+		return nil, err
+	}
+	re.Longest()
+	return &RuleLexer{
+		rules:  rules,
+		re:     re,
+		groups: groups,
+	}, nil
+}
+
+// checkRule compiles a rule's pattern on its own, it is an error for a pattern to match the empty string
+func checkRule(i int, rule Rule) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(`^(?:` + rule.Pattern + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("rule %d (%s), %s", i, rule.Type, err)
+	}
+	if re.MatchString("") == true {
+		return nil, fmt.Errorf("rule %d (%s), %q matches the empty string", i, rule.Type, rule.Pattern)
+	}
+	return re, nil
+}
+
+// Rules returns the rules the RuleLexer was compiled from
+func (rl *RuleLexer) Rules() []Rule {
+	return rl.rules
+}
+
+// Tok returns the next token matched by the rules and the remaining []byte
+func (rl *RuleLexer) Tok(buf []byte) (*Token, []byte) {
+	return rl.TokAt(buf, StartPosition())
+}
+
+// TokAt is like Tok where buf starts at pos
+func (rl *RuleLexer) TokAt(buf []byte, pos Position) (*Token, []byte) {
+	for {
+		if len(buf) == 0 {
+			return TokAt(buf, pos)
+		}
+		loc := rl.re.FindSubmatchIndex(buf)
+		if loc == nil || loc[1] == 0 {
+			return TokAt(buf, pos)
+		}
+		for i, group := range rl.groups {
+			if loc[2*group] < 0 {
+				continue
+			}
+			value := buf[0:loc[1]]
+			if rl.rules[i].Skip == true {
+				pos = pos.Advance(value)
+				buf = buf[loc[1]:]
+				break
+			}
+			return &Token{
+				Type:  rl.rules[i].Type,
+				Value: value,
+				Pos:   pos.orStart(),
+			}, buf[loc[1]:]
+		}
+	}
+}
+
+// Tokenizer re-scans tok and buf with the rules, it can be passed to Tok2() or Skip2()
+func (rl *RuleLexer) Tokenizer(tok *Token, buf []byte) (*Token, []byte) {
+	if tok.Type == EOF {
+		return tok, buf
+	}
+	return rl.TokAt(join(tok.Value, buf), tok.Pos)
+}
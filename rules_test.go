@@ -0,0 +1,93 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"testing"
+)
+
+func TestRuleLexer(t *testing.T) {
+	rl, err := NewRuleLexer(
+		Rule{Type: "Keyword", Pattern: `if|else`},
+		Rule{Type: "Ident", Pattern: `[\pL_][\pL\pN_]*`},
+		Rule{Type: "Number", Pattern: `[0-9]+(\.[0-9]+)?`},
+		Rule{Type: "Operator", Pattern: `==|=|\+`},
+		Rule{Type: Space, Pattern: `\s+`, Skip: true},
+	)
+	if err != nil {
+		t.Errorf("NewRuleLexer() failed, %s", err)
+		t.FailNow()
+	}
+	expected := []struct {
+		Type  string
+		Value string
+		Col   int
+	}{
+		{"Keyword", "if", 1},
+		{"Ident", "iffy", 4},
+		{"Operator", "==", 9},
+		{"Number", "3.14", 12},
+		{"Operator", "+", 17},
+		{"Ident", "café", 19},
+		{Punctuation, "!", 23},
+		{EOF, "", 24},
+	}
+	buf := []byte("if iffy == 3.14 + café!")
+	var token *Token
+	pos := StartPosition()
+	for i, exp := range expected {
+		token, buf = rl.TokAt(buf, pos)
+		if token.Type != exp.Type || string(token.Value) != exp.Value || token.Pos.Column != exp.Col {
+			t.Errorf("%d: expected {%q: %q} at column %d, found %s at %s", i, exp.Type, exp.Value, exp.Col, token, token.Pos)
+		}
+		pos = token.End()
+	}
+
+	// As a Tokenizer with Tok2
+	buf = []byte("  else x")
+	token, buf = Tok2(buf, rl.Tokenizer)
+	if token.Type != "Keyword" || string(token.Value) != "else" {
+		t.Errorf("expected {Keyword: else}, found %s", token)
+	}
+	_, token, buf = Skip2(Space, buf, rl.Tokenizer)
+	if token.Type != "Ident" || string(token.Value) != "x" || len(buf) != 0 {
+		t.Errorf("expected {Ident: x}, found %s, [%s]", token, buf)
+	}
+
+	if _, err := NewRuleLexer(Rule{Type: "Bad", Pattern: `(`}); err == nil {
+		t.Errorf("expected an error for an invalid pattern")
+	}
+	if _, err := NewRuleLexer(Rule{Type: "Empty", Pattern: `a*`}); err == nil {
+		t.Errorf("expected an error for a pattern matching the empty string")
+	}
+}
@@ -0,0 +1,274 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"context"
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+const (
+	// scannerBufferSize is the initial size of a Scanner's read buffer
+	scannerBufferSize = 4096
+	// maxEmptyReads is how many consecutive empty reads a Scanner tolerates before giving up
+	maxEmptyReads = 100
+)
+
+// Scanner tokenizes an io.Reader without loading it fully into memory. It refills
+// an internal buffer as needed so tokens that straddle reads are returned whole.
+// Token values returned by a Scanner remain valid after further calls.
+type Scanner struct {
+	r     io.Reader
+	buf   []byte
+	pos   Position
+	eof   bool
+	err   error
+	lexer *Lexer
+
+	ctx    context.Context
+	limits Limits
+	count  int
+	halted bool
+}
+
+// NewScanner returns a Scanner reading from r
+func NewScanner(r io.Reader) *Scanner {
+	return defaultLexer.NewScanner(r)
+}
+
+// fill reads until at least min bytes are buffered or the reader is exhausted
+func (s *Scanner) fill(min int) {
+	empty := 0
+	for len(s.buf) < min && s.eof == false {
+		if len(s.buf) == cap(s.buf) {
+			// Grow into a new array so values handed out in earlier tokens are never overwritten
+			size := len(s.buf) * 2
+			if size < scannerBufferSize {
+				size = scannerBufferSize
+			}
+			if size < min {
+				size = min
+			}
+			buf := make([]byte, len(s.buf), size)
+			copy(buf, s.buf)
+			s.buf = buf
+		}
+		n, err := s.r.Read(s.buf[len(s.buf):cap(s.buf)])
+		s.buf = s.buf[:len(s.buf)+n]
+		if err != nil {
+			s.eof = true
+			if err != io.EOF {
+				s.err = err
+			}
+			break
+		}
+		if n == 0 {
+			empty++
+			if empty >= maxEmptyReads {
+				s.eof = true
+				s.err = io.ErrNoProgress
+			}
+		} else {
+			empty = 0
+		}
+	}
+}
+
+// fillRune reads until the buffer starts with a complete code point, it doesn't wait for
+// more input than that so a Scanner over a pipe returns buffered tokens without blocking
+func (s *Scanner) fillRune() {
+	for utf8.FullRune(s.buf) == false && s.eof == false {
+		s.fill(len(s.buf) + 1)
+	}
+}
+
+// SetLimits bounds the work done by the Scanner, ctx may be nil. Once ctx is done or the
+// input exceeds limits the Scanner returns EOF tokens and Err() returns ctx.Err() or a *LimitError.
+func (s *Scanner) SetLimits(ctx context.Context, limits Limits) {
+	s.ctx, s.limits = ctx, limits
+}
+
+// halt stops the Scanner, the following tokens are EOF and Err() returns err
+func (s *Scanner) halt(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+	s.buf, s.eof, s.halted = nil, true, true
+}
+
+// stopped halts the Scanner if its context is done, reporting if the Scanner is halted
+func (s *Scanner) stopped() bool {
+	if s.halted == false && s.ctx != nil {
+		if err := s.ctx.Err(); err != nil {
+			s.halt(err)
+		}
+	}
+	return s.halted
+}
+
+// overflowed is called before reading more input for a token that spans the whole buffer,
+// it halts the Scanner if that token is already over MaxBytes or MaxTokenSize
+func (s *Scanner) overflowed() bool {
+	// Allow for an incomplete code point at the end of the buffer
+	err := s.limits.check("MaxBytes", s.limits.MaxBytes, s.pos.Offset+len(s.buf)-utf8.UTFMax, s.pos)
+	if err == nil {
+		err = s.limits.check("MaxTokenSize", s.limits.MaxTokenSize, len(s.buf)-utf8.UTFMax, s.pos)
+	}
+	if err != nil {
+		s.halt(err)
+		return true
+	}
+	return false
+}
+
+// counted checks token against the Scanner's limits, returning it or an EOF token if it exceeds them
+func (s *Scanner) counted(token *Token) *Token {
+	if token.Type == EOF {
+		return token
+	}
+	s.count++
+	if err := s.limits.token(token, s.count, s.pos.Offset); err != nil {
+		s.halt(err)
+		return &Token{Type: EOF, Value: []byte(""), Pos: token.Pos}
+	}
+	return token
+}
+
+// more reports if a result computed from buffered bytes ending with rest might change
+// once more input is read, i.e. rest could hold an incomplete UTF-8 code point
+func (s *Scanner) more(rest []byte) bool {
+	return s.eof == false && len(rest) < utf8.UTFMax
+}
+
+// Next returns the next token, an EOF token is returned once the reader is exhausted
+func (s *Scanner) Next() *Token {
+	s.fillRune()
+	s.stopped()
+	token, rest := s.lexer.TokAt(s.buf, s.pos)
+	s.buf, s.pos = rest, token.End()
+	return s.counted(token)
+}
+
+// Next2 is like Next but applies fn as Tok2() does. When fn consumes the whole
+// buffered input the buffer is enlarged and fn applied again, so fn always sees
+// enough of the input to complete its token.
+func (s *Scanner) Next2(fn Tokenizer) *Token {
+	var (
+		token *Token
+		rest  []byte
+	)
+	min := utf8.UTFMax
+	for {
+		s.fill(min)
+		s.stopped()
+		token, rest = s.lexer.Tok2At(s.buf, fn, s.pos)
+		if s.more(rest) == false {
+			break
+		}
+		if s.overflowed() == true {
+			return &Token{Type: EOF, Value: []byte(""), Pos: s.pos}
+		}
+		min = len(s.buf) * 2
+	}
+	s.buf, s.pos = rest, token.End()
+	return s.counted(token)
+}
+
+// Peek returns the next token without consuming it
+func (s *Scanner) Peek() *Token {
+	s.fillRune()
+	return s.lexer.PeekAt(s.buf, s.pos)
+}
+
+// Backup pushes token back so it will be returned by the next call to Next
+func (s *Scanner) Backup(token *Token) {
+	buf := make([]byte, 0, len(token.Value)+len(s.buf))
+	buf = append(buf, token.Value...)
+	s.buf = append(buf, s.buf...)
+	s.pos = token.Pos.orStart()
+}
+
+// Between returns the content between openValue and closeValue (see Between()),
+// reading as much input as needed to find the closing delimiter
+func (s *Scanner) Between(openValue []byte, closeValue []byte, escapeValue []byte) ([]byte, error) {
+	var (
+		between []byte
+		rest    []byte
+		err     error
+	)
+	opts := BetweenOptions{
+		Limits:  Limits{MaxDepth: s.limits.MaxDepth, MaxTokenSize: s.limits.MaxTokenSize},
+		Context: s.ctx,
+	}
+	min := utf8.UTFMax
+	for {
+		s.fill(min)
+		if s.stopped() == true {
+			return nil, s.err
+		}
+		opts.Pos = s.pos
+		between, rest, err = s.lexer.BetweenWith(openValue, closeValue, escapeValue, s.buf, opts)
+		if err == nil || s.eof == true || needsInput(err) == false {
+			break
+		}
+		if s.overflowed() == true {
+			return nil, s.err
+		}
+		min = len(s.buf) * 2
+	}
+	s.pos = s.pos.Advance(s.buf[:len(s.buf)-len(rest)])
+	s.buf = rest
+	return between, err
+}
+
+// needsInput reports if a Between error could be resolved by reading more input
+func needsInput(err error) bool {
+	var (
+		missingOpen  *MissingOpenError
+		unterminated *UnterminatedError
+		escapeAtEnd  *EscapeAtEndError
+	)
+	return errors.As(err, &missingOpen) || errors.As(err, &unterminated) || errors.As(err, &escapeAtEnd)
+}
+
+// Pos returns the position of the next token
+func (s *Scanner) Pos() Position {
+	return s.pos
+}
+
+// Err returns the first non-EOF error encountered reading input
+func (s *Scanner) Err() error {
+	return s.err
+}
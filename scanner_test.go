@@ -0,0 +1,162 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestScanner(t *testing.T) {
+	fname := path.Join("testdata", "sample-00.txt")
+	src, err := ioutil.ReadFile(fname)
+	if err != nil {
+		t.Errorf("%s, %s", fname, err)
+		t.FailNow()
+	}
+	src = append(src, []byte(" café 東京 €")...)
+
+	// A one byte reader forces every multi-byte code point to straddle a refill
+	scanner := NewScanner(iotest.OneByteReader(bytes.NewReader(src)))
+	buf := src
+	pos := StartPosition()
+	for i := 0; ; i++ {
+		var expected *Token
+		expected, buf = TokAt(buf, pos)
+		pos = expected.End()
+		peeked := scanner.Peek()
+		token := scanner.Next()
+		if token.Type != expected.Type || bytes.Equal(token.Value, expected.Value) == false || token.Pos != expected.Pos {
+			t.Errorf("%d: expected %s at %s, found %s at %s", i, expected, expected.Pos, token, token.Pos)
+		}
+		if peeked.Type != token.Type || bytes.Equal(peeked.Value, token.Value) == false {
+			t.Errorf("%d: Peek() %s != Next() %s", i, peeked, token)
+		}
+		if token.Type == EOF {
+			break
+		}
+	}
+	if scanner.Err() != nil {
+		t.Errorf("unexpected error, %s", scanner.Err())
+	}
+}
+
+func TestScannerPipe(t *testing.T) {
+	// Next and Peek return a buffered token without waiting for more input
+	r, w := io.Pipe()
+	scanner := NewScanner(r)
+	go w.Write([]byte("a"))
+	tokens := make(chan *Token)
+	go func() {
+		tokens <- scanner.Peek()
+		tokens <- scanner.Next()
+	}()
+	for _, name := range []string{"Peek", "Next"} {
+		select {
+		case token := <-tokens:
+			if string(token.Value) != "a" {
+				t.Errorf("%s() expected a, found %s", name, token)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s() blocked with a token buffered", name)
+		}
+	}
+	go func() {
+		w.Write([]byte("€"))
+		w.Close()
+	}()
+	if token := scanner.Next(); string(token.Value) != "€" {
+		t.Errorf("expected €, found %s", token)
+	}
+	if token := scanner.Next(); token.Type != EOF {
+		t.Errorf("expected EOF, found %s", token)
+	}
+}
+
+func TestScannerNext2(t *testing.T) {
+	src := strings.Repeat("naïve words straddle ", 500)
+	scanner := NewScanner(iotest.HalfReader(strings.NewReader(src)))
+	buf := []byte(src)
+	var expected *Token
+	for i := 0; ; i++ {
+		expected, buf = Tok2(buf, Words)
+		token := scanner.Next2(Words)
+		if token.Type != expected.Type || bytes.Equal(token.Value, expected.Value) == false {
+			t.Errorf("%d: expected %s, found %s", i, expected, token)
+			break
+		}
+		if token.Type == EOF {
+			break
+		}
+	}
+}
+
+func TestScannerBackupAndBetween(t *testing.T) {
+	scanner := NewScanner(iotest.OneByteReader(strings.NewReader(`x { me = "Robert {nickname} Doiel", } y`)))
+	token := scanner.Next()
+	if string(token.Value) != "x" {
+		t.Errorf("expected x, found %s", token)
+	}
+	scanner.Backup(token)
+	token = scanner.Next()
+	if string(token.Value) != "x" || token.Pos.Offset != 0 {
+		t.Errorf("expected x at offset 0 after Backup(), found %s at %+v", token, token.Pos)
+	}
+	between, err := scanner.Between([]byte("{"), []byte("}"), []byte(""))
+	if err != nil {
+		t.Errorf("Between() failed, %s", err)
+	}
+	expected := ` me = "Robert {nickname} Doiel", `
+	if string(between) != expected {
+		t.Errorf("expected [%s], found [%s]", expected, between)
+	}
+	token = scanner.Next()
+	token = scanner.Next()
+	if string(token.Value) != "y" || token.Pos.Column != 39 {
+		t.Errorf("expected y at column 39, found %s at %+v", token, token.Pos)
+	}
+
+	scanner = NewScanner(strings.NewReader("{ never closed"))
+	if _, err := scanner.Between([]byte("{"), []byte("}"), []byte("")); err == nil {
+		t.Errorf("expected an error for an unterminated region")
+	}
+	if token := scanner.Next(); token.Type != EOF {
+		t.Errorf("expected EOF, found %s", token)
+	}
+}
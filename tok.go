@@ -38,22 +38,32 @@ import (
 	"encoding/xml"
 	"fmt"
 	"regexp"
+	"sort"
+	"unicode"
+	"unicode/utf8"
 )
 
 const (
 	// Version of  tok package
 	Version = "v0.0.2"
 
-	//Base token types, these are single letter tokens, no look ahead needed
+	//Base token types, these are single character (UTF-8 code point) tokens, no look ahead needed
 
-	// Letter is an alphabetical letter (e.g. A-Z, a-z in English)
+	// Letter is an alphabetical letter (e.g. A-Z, a-z in English, é, 東) or a combining mark
 	Letter = "Letter"
 	// Numeral is a single digit
 	Numeral = "Numeral"
 	// Punctuation is any non-number, non alphametical character, non-space (e.g. periods, colons, bang, hash mark)
 	Punctuation = "Punctuation"
-	// Space characters representing white space (e.g. space, tab, new line, carriage return)
+	// Space characters representing white space (e.g. space, tab, new line, carriage return, no-break space)
 	Space = "Space"
+	// Symbol is a non-ASCII symbol (e.g. currency, math or other signs like €, ©, ≠).
+	// ASCII symbols like "$", "+" and "~" remain Punctuation.
+	Symbol = "Symbol"
+	// Invalid is a byte that does not start a valid UTF-8 encoded code point
+	Invalid = "Invalid"
+	// TooLong is returned when accumulating a token or skipped content would pass a Lexer's MaxTokenLength
+	TooLong = "TooLong"
 
 	// These are some common specialized token types provided for convientent.
 
@@ -91,13 +101,14 @@ const (
 )
 
 var (
-	// Numerals is a map of numbers as strings
+	// Numerals is a map of numbers as strings, Tok() now classifies by Unicode category
+	// so this is the ASCII subset of what is recognized as a Numeral.
 	Numerals = []byte("0123456789")
 
-	// Spaces is a map space symbols as strings
+	// Spaces is a map space symbols as strings, the ASCII subset of what Tok() recognizes as Space.
 	Spaces = []byte(" \t\r\n")
 
-	// PunctuationMarks map as strings
+	// PunctuationMarks map as strings, the ASCII subset of what Tok() recognizes as Punctuation.
 	PunctuationMarks = []byte("~!@#$%^&*()_+`-=:{}|[]\\:;\"'<>?,./")
 
 	// These map to the specialized tokens
@@ -129,13 +140,38 @@ var (
 	CloseAngleBrackets = []byte(">")
 	// AngleBrackets tokens
 	AngleBrackets = []byte("<>")
+
+	// asciiSymbols are the ASCII members of the Unicode symbol categories (Sc, Sk, Sm),
+	// they have always been treated as Punctuation.
+	asciiSymbols = &unicode.RangeTable{
+		R16: []unicode.Range16{
+			{Lo: '$', Hi: '$', Stride: 1},
+			{Lo: '+', Hi: '+', Stride: 1},
+			{Lo: '<', Hi: '>', Stride: 1},
+			{Lo: '^', Hi: '^', Stride: 1},
+			{Lo: '`', Hi: '`', Stride: 1},
+			{Lo: '|', Hi: '|', Stride: 1},
+			{Lo: '~', Hi: '~', Stride: 1},
+		},
+		LatinOffset: 7,
+	}
 )
 
-// Token structure for emitting simply tokens and value from Tok() and Tok2()
+// Token structure for emitting simply tokens and value from Tok() and Tok2(),
+// Pos is where the token's value starts in the input.
+//
+// A token's Value aliases the input buffer it was read from, so it is only valid as long
+// as the input is unchanged. The tokenizers in this package never write into their input
+// or into a token's Value, use Clone() or Owned() to keep a value independent of its input.
+//
+// Decoded is set by Tokenizers that interpret their token's value, e.g. DoubleQuoted sets it
+// to the string's content with its escapes decoded while Value holds the raw text.
 type Token struct {
 	XMLName xml.Name `json:"-"`
 	Type    string   `xml:"type" json:"type"`
 	Value   []byte   `xml:"value" json:"value"`
+	Pos     Position `xml:"pos" json:"pos"`
+	Decoded []byte   `xml:"decoded,omitempty" json:"decoded,omitempty"`
 }
 
 // TokenMap is a map of simple token names and associated array of possible bytes
@@ -144,29 +180,62 @@ type TokenMap map[string][]byte
 // Tokenizer is a function that takes a current token, looks ahead in []byte and returns a revised token and remaining []byte
 type Tokenizer func(*Token, []byte) (*Token, []byte)
 
+// Clone returns a copy of the token with its own copy of Value
+func (t *Token) Clone() *Token {
+	token := *t
+	return token.Owned()
+}
+
+// Owned replaces the token's Value and Decoded with copies so they no longer alias the input,
+// returning the token
+func (t *Token) Owned() *Token {
+	if t.Value != nil {
+		t.Value = append(make([]byte, 0, len(t.Value)), t.Value...)
+	}
+	if t.Decoded != nil {
+		t.Decoded = append(make([]byte, 0, len(t.Decoded)), t.Decoded...)
+	}
+	return t
+}
+
 // String returns a human readable Token struct
 func (t *Token) String() string {
 	return fmt.Sprintf("{%q: %q}", t.Type, t.Value)
 }
 
-// IsSpace checks to see if []byte is a space or not
-func IsSpace(b []byte) bool {
-	for _, val := range b {
-		if bytes.IndexByte(Spaces, val) == -1 {
+// eachRune applies fn to each UTF-8 code point in b, it returns false if b holds
+// an invalid encoding or fn returns false for any code point.
+func eachRune(b []byte, fn func(rune) bool) bool {
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size <= 1 {
+			return false
+		}
+		if fn(r) == false {
 			return false
 		}
+		b = b[size:]
 	}
 	return true
 }
 
+// IsSpace checks to see if []byte is a space or not
+func IsSpace(b []byte) bool {
+	return eachRune(b, unicode.IsSpace)
+}
+
 // IsPunctuation checks to see if []byte is some punctuation or not
 func IsPunctuation(b []byte) bool {
-	for _, val := range b {
-		if bytes.IndexByte(PunctuationMarks, val) == -1 {
-			return false
-		}
-	}
-	return true
+	return eachRune(b, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.Is(asciiSymbols, r)
+	})
+}
+
+// IsSymbol checks to see if []byte is a non-ASCII symbol (e.g. currency or math sign) or not
+func IsSymbol(b []byte) bool {
+	return eachRune(b, func(r rune) bool {
+		return r >= utf8.RuneSelf && unicode.IsSymbol(r)
+	})
 }
 
 // IsNumeral checks to see if []byte is a number or not
@@ -174,239 +243,159 @@ func IsNumeral(b []byte) bool {
 	if bytes.Count(b, []byte(".")) > 1 {
 		return false
 	}
-	for _, val := range b {
-		if bytes.IndexByte([]byte("0123456789."), val) == -1 {
-			return false
-		}
-	}
-	return true
+	return eachRune(b, func(r rune) bool {
+		return r == '.' || unicode.IsDigit(r)
+	})
 }
 
-// Tok is a naive tokenizer that looks only at the next character by shifting it off the []byte and returning a token found with remaining []byte
+// IsLetter checks to see if []byte is made of letters (including combining marks) or not
+func IsLetter(b []byte) bool {
+	return eachRune(b, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsMark(r)
+	})
+}
+
+// Tok is a naive tokenizer that looks only at the next character (a UTF-8 code point) by shifting it off the []byte
+// and returning a token found with remaining []byte. Bytes that are not valid UTF-8 are returned one at a time
+// as Invalid tokens. Token positions are relative to the start of buf.
 func Tok(buf []byte) (*Token, []byte) {
-	var (
-		s []byte
-	)
-	if len(buf) == 0 {
-		return &Token{
-			Type:  EOF,
-			Value: []byte(""),
-		}, nil
-	}
-	s, buf = buf[0:1], buf[1:]
-	switch {
-	case IsPunctuation(s) == true:
-		return &Token{
-			Type:  Punctuation,
-			Value: s,
-		}, buf
-	case IsSpace(s) == true:
-		return &Token{
-			Type:  Space,
-			Value: s,
-		}, buf
-	case IsNumeral(s) == true:
-		return &Token{
-			Type:  Numeral,
-			Value: s,
-		}, buf
-	default:
-		return &Token{
-			Type:  Letter,
-			Value: s,
-		}, buf
-	}
+	return TokAt(buf, StartPosition())
+}
+
+// TokAt is like Tok but the returned Token's Pos is pos, the position of buf[0] in the original input
+func TokAt(buf []byte, pos Position) (*Token, []byte) {
+	return defaultLexer.TokAt(buf, pos)
 }
 
 // TokenFromMap, revaluates token type against a map of type names and byte arrays
-// returns modified Token
+// returns modified Token. Types are checked in sorted order so the result is the same
+// from run to run, use an OrderedTokenMap to control which type wins.
 func TokenFromMap(t *Token, m map[string][]byte) *Token {
-	for k, v := range m {
-		if bytes.Contains(v, t.Value) {
-			return &Token{
-				Type:  k,
-				Value: t.Value,
-			}
-		}
-	}
 	return &Token{
-		Type:  t.Type,
+		Type:  mapType(m, sortedKeys(m), t.Value, t.Type),
 		Value: t.Value,
+		Pos:   t.Pos,
+	}
+}
+
+// sortedKeys returns the token types of m in the order TokenFromMap checks them
+func sortedKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
+	sort.Strings(keys)
+	return keys
+}
+
+// mapType returns the first of the sorted keys whose map entry contains value, otherwise tokenType
+func mapType(m map[string][]byte, keys []string, value []byte, tokenType string) string {
+	for _, k := range keys {
+		if bytes.Contains(m[k], value) {
+			return k
+		}
+	}
+	return tokenType
 }
 
 // Tok2 provides an easy to implement look ahead tokenizer by defining a look ahead function
 func Tok2(buf []byte, fn Tokenizer) (*Token, []byte) {
-	tok, rest := Tok(buf)
-	return fn(tok, rest)
+	return defaultLexer.Tok2(buf, fn)
 }
 
-// Skip provides a means to advance to the next non-target Token.
-func Skip(tokenType string, buf []byte) ([]byte, *Token, []byte) {
-	var (
-		skipped []byte
-		token   *Token
-	)
-	// Handle an empty buffer gracefully
-	if len(buf) == 0 {
-		token.Type = EOF
-		token.Value = []byte("")
-		return skipped, token, buf
-	}
-	for {
-		token, buf = Tok(buf)
-		if token.Type != tokenType {
-			break
-		}
-		skipped = append(skipped[:], token.Value[:]...)
-		if len(buf) == 0 {
-			break
+// Tok2At is like Tok2 but the token handed to fn starts at pos
+func Tok2At(buf []byte, fn Tokenizer, pos Position) (*Token, []byte) {
+	return defaultLexer.Tok2At(buf, fn, pos)
+}
+
+// Chain returns a Tokenizer applying each of fns in turn, each is handed the token and
+// remaining buffer returned by the one before (e.g. Chain(Words, keywords.Tokenizer))
+func Chain(fns ...Tokenizer) Tokenizer {
+	return func(tok *Token, buf []byte) (*Token, []byte) {
+		for _, fn := range fns {
+			tok, buf = fn(tok, buf)
 		}
+		return tok, buf
 	}
+}
 
-	return skipped, token, buf
+// Skip provides a means to advance to the next non-target Token.
+func Skip(tokenType string, buf []byte) ([]byte, *Token, []byte) {
+	return defaultLexer.Skip(tokenType, buf)
 }
 
+// SkipAt is like Skip where buf starts at pos, the returned Token's Pos accounts for the skipped content
+func SkipAt(tokenType string, buf []byte, pos Position) ([]byte, *Token, []byte) {
+	return defaultLexer.SkipAt(tokenType, buf, pos)
+}
+
+// Skip2 is like Skip but uses Tok2() with fn to produce tokens
 func Skip2(tokenType string, buf []byte, fn Tokenizer) ([]byte, *Token, []byte) {
-	var (
-		skipped []byte
-		token   *Token
-	)
-	// Handle an empty buffer gracefully
-	if len(buf) == 0 {
-		token.Type = EOF
-		token.Value = []byte("")
-		return skipped, token, buf
-	}
-	for {
-		token, buf = Tok2(buf, fn)
-		if token.Type != tokenType {
-			break
-		}
-		skipped = append(skipped[:], token.Value[:]...)
-		if len(buf) == 0 {
-			break
-		}
-	}
+	return defaultLexer.Skip2(tokenType, buf, fn)
+}
 
-	return skipped, token, buf
+// Skip2At is like Skip2 where buf starts at pos
+func Skip2At(tokenType string, buf []byte, fn Tokenizer, pos Position) ([]byte, *Token, []byte) {
+	return defaultLexer.Skip2At(tokenType, buf, fn, pos)
 }
 
 // Peek generates a token without consuming the buffer
 func Peek(buf []byte) *Token {
-	var (
-		s []byte
-	)
-	if len(buf) == 0 {
-		return &Token{
-			Type:  EOF,
-			Value: []byte(""),
-		}
-	}
-	s = buf[0:1]
-	switch {
-	case IsPunctuation(s) == true:
-		return &Token{
-			Type:  Punctuation,
-			Value: s,
-		}
-	case IsSpace(s) == true:
-		return &Token{
-			Type:  Space,
-			Value: s,
-		}
-	case IsNumeral(s) == true:
-		return &Token{
-			Type:  Numeral,
-			Value: s,
-		}
-	default:
-		return &Token{
-			Type:  Letter,
-			Value: s,
-		}
-	}
+	return defaultLexer.Peek(buf)
+}
+
+// PeekAt is like Peek where buf starts at pos
+func PeekAt(buf []byte, pos Position) *Token {
+	return defaultLexer.PeekAt(buf, pos)
 }
 
 // Between returns the buf between two delimiters (e.g. curly braces)
 func Between(openValue []byte, closeValue []byte, escapeValue []byte, buf []byte) ([]byte, []byte, error) {
-	var (
-		between        []byte
-		hasEscapeValue bool
-		token          *Token
-		isQuote        bool
-	)
-
-	isQuote = bytes.Equal(openValue, closeValue)
-	if len(escapeValue) > 0 {
-		hasEscapeValue = true
-	}
+	return defaultLexer.Between(openValue, closeValue, escapeValue, buf)
+}
 
-	quoteCount := 0
-	// Advance to start of Between token types
-	if len(buf) == 0 {
-		return between, buf, fmt.Errorf("missing opening %s", openValue)
-	}
-	for {
-		if len(buf) == 0 {
-			return between, buf, fmt.Errorf("missing closing %s", closeValue)
-		}
-		token, buf = Tok(buf)
-		switch {
-		case hasEscapeValue == true && bytes.Equal(escapeValue, token.Value):
-			between = append(between[:], token.Value[:]...)
-			token, buf = Tok(buf)
-			between = append(between[:], token.Value[:]...)
-		case isQuote == true && bytes.Equal(openValue, token.Value):
-			if quoteCount == 0 {
-				quoteCount++
-			} else {
-				quoteCount--
-			}
-			if quoteCount > 1 {
-				between = append(between[:], token.Value[:]...)
-			}
-			if quoteCount == 0 {
-				return between, buf, nil
-			}
-		case bytes.Equal(openValue, token.Value):
-			quoteCount++
-			if quoteCount > 1 {
-				between = append(between[:], token.Value[:]...)
-			}
-		case bytes.Equal(closeValue, token.Value):
-			quoteCount--
-			if quoteCount == 0 {
-				return between, buf, nil
-			}
-			between = append(between[:], token.Value[:]...)
-		default:
-			if quoteCount > 0 {
-				between = append(between[:], token.Value[:]...)
-			}
-		}
-	}
+// BetweenAt is like Between where buf starts at pos, errors report the position of
+// the opening delimiter
+func BetweenAt(openValue []byte, closeValue []byte, escapeValue []byte, buf []byte, pos Position) ([]byte, []byte, error) {
+	return defaultLexer.BetweenAt(openValue, closeValue, escapeValue, buf, pos)
+}
 
-	return between, buf, nil
+// BetweenWith is like Between adjusted by opts (e.g. to recover from errors), errors are
+// typed (e.g. *UnterminatedError) and carry the position of the opening delimiter
+func BetweenWith(openValue []byte, closeValue []byte, escapeValue []byte, buf []byte, opts BetweenOptions) ([]byte, []byte, error) {
+	return defaultLexer.BetweenWith(openValue, closeValue, escapeValue, buf, opts)
 }
 
-// Backup pushes a Token back onto the front of a Buffer
+// Backup pushes a Token back onto the front of a Buffer. Neither the token's value nor buf
+// are written to, when the token was read just before buf the result shares their array.
 func Backup(token *Token, buf []byte) []byte {
-	return append(token.Value[:], buf[:]...)
+	return join(token.Value, buf)
+}
+
+// join returns a followed by b. When b immediately follows a in the same array (e.g. a token's
+// value and the buffer Tok() returned with it) the result shares it, otherwise a new array is made.
+func join(a, b []byte) []byte {
+	switch {
+	case len(b) == 0:
+		return a
+	case len(a) == 0:
+		return b
+	case cap(a) > len(a) && &a[:len(a)+1][len(a)] == &b[0] && cap(a)-len(a) >= len(b):
+		return a[:len(a)+len(b)]
+	}
+	buf := make([]byte, 0, len(a)+len(b))
+	buf = append(buf, a...)
+	return append(buf, b...)
+}
+
+// BackupAt is like Backup but also returns the position the restored buffer starts at (the token's Pos)
+func BackupAt(token *Token, buf []byte) ([]byte, Position) {
+	return Backup(token, buf), token.Pos.orStart()
 }
 
 // Words is an example of implementing a Tokenizer function
 func Words(tok *Token, buf []byte) (*Token, []byte) {
-	if tok.Type == Letter || tok.Type == Word {
-		// Get the next Token
-		newTok, newBuf := Tok(buf)
-		if newTok.Type == Letter {
-			tok.Type = Word
-			tok.Value = append(tok.Value, newTok.Value[0])
-			tok, buf = Words(tok, newBuf)
-		}
-	}
-	return tok, buf
+	return defaultLexer.Words(tok, buf)
 }
 
 // Next splits a buffer once at the first matching []byte encountered
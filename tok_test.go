@@ -370,3 +370,147 @@ fix and six
 		OK(i, exp, nl)
 	}
 }
+
+func TestUnicode(t *testing.T) {
+	buf := []byte("café 東京 4€\xff!")
+	expected := []struct {
+		Type  string
+		Value string
+	}{
+		{Letter, "c"},
+		{Letter, "a"},
+		{Letter, "f"},
+		{Letter, "é"},
+		{Space, " "},
+		{Letter, "東"},
+		{Letter, "京"},
+		{Space, " "},
+		{Numeral, "4"},
+		{Symbol, "€"},
+		{Invalid, "\xff"},
+		{Punctuation, "!"},
+		{EOF, ""},
+	}
+	var token *Token
+	for i, exp := range expected {
+		peeked := Peek(buf)
+		token, buf = Tok(buf)
+		if token.Type != exp.Type || string(token.Value) != exp.Value {
+			t.Errorf("%d: expected {%q: %q}, found %s", i, exp.Type, exp.Value, token)
+		}
+		if peeked.Type != token.Type || bytes.Equal(peeked.Value, token.Value) == false {
+			t.Errorf("%d: Peek() %s != Tok() %s", i, peeked, token)
+		}
+	}
+
+	src := []byte("naïve café, déjà vu")
+	for _, word := range []string{"naïve", "café", "déjà", "vu"} {
+		_, token, src = Skip2(Space, src, Words)
+		if token.Type != Word || string(token.Value) != word {
+			t.Errorf("expected {%q: %q}, found %s", Word, word, token)
+		}
+		if len(src) > 0 && IsPunctuation(src[0:1]) == true {
+			_, src = Tok(src)
+		}
+	}
+
+	between, _, err := Between([]byte("«"), []byte("»"), []byte(""), []byte("dit «bonjour» et"))
+	if err != nil {
+		t.Errorf("Between() failed, %s", err)
+	}
+	if string(between) != "bonjour" {
+		t.Errorf("expected [bonjour], found [%s]", between)
+	}
+
+	if IsSpace([]byte(" 　")) == false {
+		t.Errorf("expected no-break and ideographic spaces to be Space")
+	}
+	if IsNumeral([]byte("١٢٣")) == false {
+		t.Errorf("expected Arabic-Indic digits to be a Numeral")
+	}
+	if IsPunctuation([]byte("¿¡")) == false {
+		t.Errorf("expected inverted marks to be Punctuation")
+	}
+}
+
+func TestTokenOwnership(t *testing.T) {
+	token := &Token{Type: Word, Value: []byte("abc"), Pos: StartPosition()}
+	clone := token.Clone()
+	clone.Value[0] = 'x'
+	if string(token.Value) != "abc" || clone.Type != Word || clone.Pos != token.Pos {
+		t.Errorf("expected the clone to have its own value, found %s and %s", token, clone)
+	}
+	src := []byte("abc def")
+	token, _ = Tok2(src, Words)
+	if token.Owned() != token {
+		t.Errorf("expected Owned() to return the token")
+	}
+	src[0] = 'x'
+	if string(token.Value) != "abc" {
+		t.Errorf("expected an owned value, found %s", token.Value)
+	}
+}
+
+func TestInputNotMutated(t *testing.T) {
+	src := []byte("alpha beta, {gamma} 42 δέλτα")
+	original := string(src)
+	check := func(label string) {
+		if string(src) != original {
+			t.Errorf("%s mutated the input, found [%s]", label, src)
+		}
+	}
+
+	// Backup of a token whose value has spare capacity over other data
+	token, rest := Tok2(src[0:3], Words)
+	buf := Backup(token, []byte("ZZ"))
+	if string(buf) != "alpZZ" {
+		t.Errorf("expected [alpZZ], found [%s]", buf)
+	}
+	check("Backup")
+	token, rest = Tok2(src, Words)
+	if buf = Backup(token, rest); string(buf) != original {
+		t.Errorf("expected Backup to restore the input, found [%s]", buf)
+	}
+	check("Backup")
+
+	for range All(src) {
+	}
+	for range AllWith(src, Words) {
+	}
+	check("All")
+	Skip2(Word, src, Words)
+	Skip(Letter, src)
+	check("Skip")
+	Between([]byte("{"), []byte("}"), nil, src)
+	BetweenWith([]byte("{"), []byte("}"), nil, src, BetweenOptions{Inclusive: true, Unescape: CUnescape})
+	FindAllBetween([]byte("{"), []byte("}"), nil, src)
+	ReplaceBetween([]byte("{"), []byte("}"), nil, src, func(region *Region) []byte {
+		return []byte("replaced")
+	})
+	check("Between")
+
+	m := NewOrderedTokenMap()
+	m.Add("Alpha", 0, []byte("alpha"))
+	m.Tokenizer(&Token{Value: src[0:1]}, src[1:])
+	check("OrderedTokenMap")
+
+	rl, err := NewRuleLexer(Rule{Type: Word, Pattern: `\pL+`}, Rule{Type: Space, Pattern: `\s+`, Skip: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range AllWith(src, rl.Tokenizer) {
+	}
+	dfa, err := CompileDFA(Rule{Type: Word, Pattern: `\pL+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range AllWith(src, dfa.Tokenizer) {
+	}
+	check("Tokenizer")
+
+	var reused Token
+	for buf := src; len(buf) > 0; {
+		buf = Tok2Into(&reused, buf, Words, StartPosition())
+	}
+	check("Tok2Into")
+}
@@ -0,0 +1,268 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// TokenMapEntry is a token type, its priority and the values (one or more bytes each) that map to it
+type TokenMapEntry struct {
+	Type     string
+	Priority int
+	Values   [][]byte
+}
+
+// Overlap describes a value claimed by more than one token type. Types are listed
+// in the order they are resolved, Ambiguous is true when the first two share a priority.
+type Overlap struct {
+	Value     []byte
+	Types     []string
+	Ambiguous bool
+}
+
+// String returns a human readable Overlap
+func (o Overlap) String() string {
+	return fmt.Sprintf("%q claimed by %s", o.Value, strings.Join(o.Types, ", "))
+}
+
+// TokenMapError is returned by OrderedTokenMap.Validate() when values are ambiguous
+type TokenMapError struct {
+	Overlaps []Overlap
+}
+
+// Error implements the error interface
+func (e *TokenMapError) Error() string {
+	s := make([]string, len(e.Overlaps))
+	for i, o := range e.Overlaps {
+		s[i] = o.String()
+	}
+	return fmt.Sprintf("ambiguous token map, %s", strings.Join(s, "; "))
+}
+
+// candidate is a single value of an entry, index is the entry's insertion order
+type candidate struct {
+	value    []byte
+	typ      string
+	priority int
+	index    int
+}
+
+// OrderedTokenMap is a deterministic alternative to TokenMap. Values may be more than one
+// byte long, the longest matching value wins and ties are broken by the higher Priority
+// then by the order entries were added.
+type OrderedTokenMap struct {
+	entries []TokenMapEntry
+	// byFirst holds the candidates for each leading byte sorted in resolution order
+	byFirst map[byte][]candidate
+	maxLen  int
+}
+
+// NewOrderedTokenMap returns an empty OrderedTokenMap
+func NewOrderedTokenMap() *OrderedTokenMap {
+	return &OrderedTokenMap{
+		byFirst: map[byte][]candidate{},
+	}
+}
+
+// Add maps each of values to tokenType with the given priority
+func (m *OrderedTokenMap) Add(tokenType string, priority int, values ...[]byte) {
+	index := len(m.entries)
+	m.entries = append(m.entries, TokenMapEntry{
+		Type:     tokenType,
+		Priority: priority,
+		Values:   values,
+	})
+	for _, value := range values {
+		if len(value) == 0 {
+			continue
+		}
+		c := candidate{value: value, typ: tokenType, priority: priority, index: index}
+		l := m.byFirst[value[0]]
+		l = append(l, c)
+		sort.SliceStable(l, func(i, j int) bool {
+			return resolvesBefore(l[i], l[j])
+		})
+		m.byFirst[value[0]] = l
+		if len(value) > m.maxLen {
+			m.maxLen = len(value)
+		}
+	}
+}
+
+// AddSet maps each UTF-8 code point in set to tokenType, like a TokenMap entry (e.g. CurlyBrackets)
+func (m *OrderedTokenMap) AddSet(tokenType string, priority int, set []byte) {
+	values := [][]byte{}
+	for len(set) > 0 {
+		_, size := utf8.DecodeRune(set)
+		values = append(values, set[0:size])
+		set = set[size:]
+	}
+	m.Add(tokenType, priority, values...)
+}
+
+// resolvesBefore orders candidates longest value first, then highest priority, then insertion order
+func resolvesBefore(a, b candidate) bool {
+	if len(a.value) != len(b.value) {
+		return len(a.value) > len(b.value)
+	}
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	return a.index < b.index
+}
+
+// Entries returns the entries in the order they were added
+func (m *OrderedTokenMap) Entries() []TokenMapEntry {
+	return m.entries
+}
+
+// Overlaps returns every value claimed by more than one token type
+func (m *OrderedTokenMap) Overlaps() []Overlap {
+	var (
+		overlaps []Overlap
+		keys     []int
+	)
+	for k := range m.byFirst {
+		keys = append(keys, int(k))
+	}
+	sort.Ints(keys)
+	for _, k := range keys {
+		l := m.byFirst[byte(k)]
+		seen := map[string]bool{}
+		for i, c := range l {
+			if seen[string(c.value)] == true {
+				continue
+			}
+			seen[string(c.value)] = true
+			o := Overlap{Value: c.value, Types: []string{c.typ}}
+			for _, other := range l[i+1:] {
+				if bytes.Equal(c.value, other.value) && other.typ != c.typ {
+					if len(o.Types) == 1 && other.priority == c.priority {
+						o.Ambiguous = true
+					}
+					o.Types = append(o.Types, other.typ)
+				}
+			}
+			if len(o.Types) > 1 {
+				overlaps = append(overlaps, o)
+			}
+		}
+	}
+	return overlaps
+}
+
+// Validate returns a *TokenMapError if any value is claimed by two token types with
+// the same priority, run it before tokenizing to catch ambiguous entries
+func (m *OrderedTokenMap) Validate() error {
+	var ambiguous []Overlap
+	for _, o := range m.Overlaps() {
+		if o.Ambiguous == true {
+			ambiguous = append(ambiguous, o)
+		}
+	}
+	if len(ambiguous) > 0 {
+		return &TokenMapError{Overlaps: ambiguous}
+	}
+	return nil
+}
+
+// Match returns the token type and length of the longest value that prefixes buf,
+// ok is false if no value matches
+func (m *OrderedTokenMap) Match(buf []byte) (string, int, bool) {
+	if len(buf) == 0 {
+		return "", 0, false
+	}
+	for _, c := range m.byFirst[buf[0]] {
+		if bytes.HasPrefix(buf, c.value) {
+			return c.typ, len(c.value), true
+		}
+	}
+	return "", 0, false
+}
+
+// Lookup returns the token type whose value exactly equals value
+func (m *OrderedTokenMap) Lookup(value []byte) (string, bool) {
+	if len(value) == 0 {
+		return "", false
+	}
+	for _, c := range m.byFirst[value[0]] {
+		if bytes.Equal(c.value, value) {
+			return c.typ, true
+		}
+	}
+	return "", false
+}
+
+// Retype is the OrderedTokenMap equivalent of TokenFromMap, it returns a copy of t
+// with the type of the entry matching t's value exactly
+func (m *OrderedTokenMap) Retype(t *Token) *Token {
+	tokenType := t.Type
+	if typ, ok := m.Lookup(t.Value); ok == true {
+		tokenType = typ
+	}
+	return &Token{
+		Type:  tokenType,
+		Value: t.Value,
+		Pos:   t.Pos,
+	}
+}
+
+// Tokenizer extends tok with the longest value in the map that starts with tok's value,
+// consuming the extra bytes from buf. It can be passed to Tok2().
+func (m *OrderedTokenMap) Tokenizer(tok *Token, buf []byte) (*Token, []byte) {
+	if len(tok.Value) == 0 {
+		return tok, buf
+	}
+	head := tok.Value
+	if m.maxLen > len(tok.Value) && len(buf) > 0 {
+		extra := m.maxLen - len(tok.Value)
+		if extra > len(buf) {
+			extra = len(buf)
+		}
+		head = join(tok.Value, buf[0:extra])
+	}
+	typ, n, ok := m.Match(head)
+	if ok == false || n < len(tok.Value) {
+		return tok, buf
+	}
+	return &Token{
+		Type:  typ,
+		Value: head[0:n],
+		Pos:   tok.Pos,
+	}, buf[n-len(tok.Value):]
+}
@@ -0,0 +1,110 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTokenFromMapDeterministic(t *testing.T) {
+	m := TokenMap{
+		OpenCurlyBracket: OpenCurlyBrackets,
+		CurlyBracket:     CurlyBrackets,
+	}
+	token, _ := Tok([]byte("{"))
+	first := TokenFromMap(token, m).Type
+	for i := 0; i < 100; i++ {
+		if found := TokenFromMap(token, m).Type; found != first {
+			t.Errorf("%d: TokenFromMap() changed from %s to %s", i, first, found)
+		}
+	}
+}
+
+func TestOrderedTokenMap(t *testing.T) {
+	m := NewOrderedTokenMap()
+	m.AddSet(CurlyBracket, 0, CurlyBrackets)
+	m.AddSet(OpenCurlyBracket, 1, OpenCurlyBrackets)
+	m.Add("Arrow", 0, []byte("->"))
+	m.Add("Minus", 0, []byte("-"))
+	m.Add("Template", 0, []byte("{{"))
+	if err := m.Validate(); err != nil {
+		t.Errorf("unexpected error, %s", err)
+	}
+	overlaps := m.Overlaps()
+	if len(overlaps) != 1 || string(overlaps[0].Value) != "{" || overlaps[0].Types[0] != OpenCurlyBracket || overlaps[0].Ambiguous == true {
+		t.Errorf("expected { overlap resolved to %s, found %+v", OpenCurlyBracket, overlaps)
+	}
+
+	token, _ := Tok([]byte("{"))
+	if retyped := m.Retype(token); retyped.Type != OpenCurlyBracket {
+		t.Errorf("expected %s, found %s", OpenCurlyBracket, retyped)
+	}
+	token, _ = Tok([]byte("}"))
+	if retyped := m.Retype(token); retyped.Type != CurlyBracket {
+		t.Errorf("expected %s, found %s", CurlyBracket, retyped)
+	}
+
+	buf := []byte("a->b-{{c}")
+	expected := []struct {
+		Type  string
+		Value string
+	}{
+		{Letter, "a"},
+		{"Arrow", "->"},
+		{Letter, "b"},
+		{"Minus", "-"},
+		{"Template", "{{"},
+		{Letter, "c"},
+		{CurlyBracket, "}"},
+		{EOF, ""},
+	}
+	for i, exp := range expected {
+		token, buf = Tok2(buf, m.Tokenizer)
+		if token.Type != exp.Type || string(token.Value) != exp.Value {
+			t.Errorf("%d: expected {%q: %q}, found %s", i, exp.Type, exp.Value, token)
+		}
+	}
+
+	m.Add("Dash", 0, []byte("-"))
+	err := m.Validate()
+	var mapErr *TokenMapError
+	if errors.As(err, &mapErr) == false {
+		t.Errorf("expected a *TokenMapError, found %v", err)
+	} else if len(mapErr.Overlaps) != 1 || string(mapErr.Overlaps[0].Value) != "-" {
+		t.Errorf("expected - to be ambiguous, found %+v", mapErr.Overlaps)
+	}
+	if typ, n, ok := m.Match([]byte("-x")); ok == false || typ != "Minus" || n != 1 {
+		t.Errorf("expected ambiguous ties to resolve in insertion order, found %s %d %t", typ, n, ok)
+	}
+}
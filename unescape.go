@@ -0,0 +1,278 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Unescaper decodes the escape sequences in content extracted by Between(), e.g. CUnescape
+type Unescaper func([]byte) ([]byte, error)
+
+// EscapeError describes a malformed escape sequence found by an Unescaper
+type EscapeError struct {
+	// Offset is the byte offset of the sequence in the unescaped content
+	Offset int
+	// Sequence holds the bytes of the malformed sequence
+	Sequence []byte
+	// Reason describes what is wrong
+	Reason string
+	// Pos is the position of the sequence in the input when known (e.g. from BetweenWith())
+	Pos Position
+}
+
+// Error implements the error interface
+func (e *EscapeError) Error() string {
+	if e.Pos.IsValid() == true {
+		return fmt.Sprintf("invalid escape %q at %s, %s", e.Sequence, e.Pos, e.Reason)
+	}
+	return fmt.Sprintf("invalid escape %q at offset %d, %s", e.Sequence, e.Offset, e.Reason)
+}
+
+// simpleEscapes are the single character C/Go escapes
+var simpleEscapes = map[byte]byte{
+	'a':  '\a',
+	'b':  '\b',
+	'f':  '\f',
+	'n':  '\n',
+	'r':  '\r',
+	't':  '\t',
+	'v':  '\v',
+	'\\': '\\',
+	'\'': '\'',
+	'"':  '"',
+	'?':  '?',
+}
+
+// hexValue decodes b as hexadecimal digits, ok is false if any byte is not a hex digit
+func hexValue(b []byte) (rune, bool) {
+	var r rune
+	for _, c := range b {
+		switch {
+		case c >= '0' && c <= '9':
+			r = r*16 + rune(c-'0')
+		case c >= 'a' && c <= 'f':
+			r = r*16 + rune(c-'a'+10)
+		case c >= 'A' && c <= 'F':
+			r = r*16 + rune(c-'A'+10)
+		default:
+			return 0, false
+		}
+	}
+	return r, true
+}
+
+// escapeError returns an *EscapeError for the sequence b[start:end]
+func escapeError(b []byte, start, end int, reason string) error {
+	if end > len(b) {
+		end = len(b)
+	}
+	return &EscapeError{Offset: start, Sequence: b[start:end], Reason: reason}
+}
+
+// CUnescape decodes C and Go style escapes, \a \b \f \n \r \t \v \\ \' \" \?, \xHH, octal \ooo
+// (one to three digits), \uHHHH and \UHHHHHHHH
+func CUnescape(b []byte) ([]byte, error) {
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); {
+		if b[i] != '\\' {
+			out = append(out, b[i])
+			i++
+			continue
+		}
+		if i+1 >= len(b) {
+			return nil, escapeError(b, i, i+1, "escape at end of input")
+		}
+		e := b[i+1]
+		if c, ok := simpleEscapes[e]; ok == true {
+			out = append(out, c)
+			i += 2
+			continue
+		}
+		switch {
+		case e == 'x':
+			if i+4 > len(b) {
+				return nil, escapeError(b, i, i+4, "\\x needs two hex digits")
+			}
+			v, ok := hexValue(b[i+2 : i+4])
+			if ok == false {
+				return nil, escapeError(b, i, i+4, "\\x needs two hex digits")
+			}
+			out = append(out, byte(v))
+			i += 4
+		case e == 'u' || e == 'U':
+			n := 4
+			if e == 'U' {
+				n = 8
+			}
+			if i+2+n > len(b) {
+				return nil, escapeError(b, i, i+2+n, fmt.Sprintf("\\%c needs %d hex digits", e, n))
+			}
+			r, ok := hexValue(b[i+2 : i+2+n])
+			if ok == false {
+				return nil, escapeError(b, i, i+2+n, fmt.Sprintf("\\%c needs %d hex digits", e, n))
+			}
+			if utf8.ValidRune(r) == false {
+				return nil, escapeError(b, i, i+2+n, "not a valid code point")
+			}
+			out = utf8.AppendRune(out, r)
+			i += 2 + n
+		case e >= '0' && e <= '7':
+			v, j := 0, i+1
+			for ; j < len(b) && j < i+4 && b[j] >= '0' && b[j] <= '7'; j++ {
+				v = v*8 + int(b[j]-'0')
+			}
+			if v > 255 {
+				return nil, escapeError(b, i, j, "octal value is larger than 255")
+			}
+			out = append(out, byte(v))
+			i = j
+		default:
+			return nil, escapeError(b, i, i+2, "unknown escape")
+		}
+	}
+	return out, nil
+}
+
+// jsonEscapes are the single character JSON escapes
+var jsonEscapes = map[byte]byte{
+	'"':  '"',
+	'\\': '\\',
+	'/':  '/',
+	'b':  '\b',
+	'f':  '\f',
+	'n':  '\n',
+	'r':  '\r',
+	't':  '\t',
+}
+
+// JSONUnescape decodes JSON string escapes, \" \\ \/ \b \f \n \r \t and \uHHHH including
+// UTF-16 surrogate pairs, an unpaired surrogate is an error
+func JSONUnescape(b []byte) ([]byte, error) {
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); {
+		if b[i] != '\\' {
+			out = append(out, b[i])
+			i++
+			continue
+		}
+		if i+1 >= len(b) {
+			return nil, escapeError(b, i, i+1, "escape at end of input")
+		}
+		e := b[i+1]
+		if c, ok := jsonEscapes[e]; ok == true {
+			out = append(out, c)
+			i += 2
+			continue
+		}
+		if e != 'u' {
+			return nil, escapeError(b, i, i+2, "unknown escape")
+		}
+		if i+6 > len(b) {
+			return nil, escapeError(b, i, i+6, "\\u needs four hex digits")
+		}
+		r, ok := hexValue(b[i+2 : i+6])
+		if ok == false {
+			return nil, escapeError(b, i, i+6, "\\u needs four hex digits")
+		}
+		switch {
+		case r >= 0xD800 && r < 0xDC00:
+			if i+12 > len(b) || b[i+6] != '\\' || b[i+7] != 'u' {
+				return nil, escapeError(b, i, i+6, "high surrogate is not followed by a low surrogate")
+			}
+			low, ok := hexValue(b[i+8 : i+12])
+			if ok == false || low < 0xDC00 || low > 0xDFFF {
+				return nil, escapeError(b, i, i+12, "high surrogate is not followed by a low surrogate")
+			}
+			out = utf8.AppendRune(out, utf16.DecodeRune(r, low))
+			i += 12
+		case r >= 0xDC00 && r <= 0xDFFF:
+			return nil, escapeError(b, i, i+6, "low surrogate without a high surrogate")
+		default:
+			out = utf8.AppendRune(out, r)
+			i += 6
+		}
+	}
+	return out, nil
+}
+
+// SQLUnescape decodes SQL string literal content where each quote is written as two single quotes,
+// use it with BetweenOptions.DoubledClose so Between keeps the doubled quotes in the region
+func SQLUnescape(b []byte) ([]byte, error) {
+	return DoubledQuoteUnescaper('\'')(b)
+}
+
+// DoubledQuoteUnescaper returns an Unescaper collapsing each doubled quote into one,
+// a quote that isn't doubled is an error
+func DoubledQuoteUnescaper(quote byte) Unescaper {
+	return func(b []byte) ([]byte, error) {
+		out := make([]byte, 0, len(b))
+		for i := 0; i < len(b); i++ {
+			if b[i] == quote {
+				if i+1 >= len(b) || b[i+1] != quote {
+					return nil, escapeError(b, i, i+1, "quote is not doubled")
+				}
+				i++
+			}
+			out = append(out, b[i])
+		}
+		return out, nil
+	}
+}
+
+// ShellUnescape decodes the backslash escapes of POSIX shell double quoted strings, a backslash
+// escapes $ ` " \ and removes a following new line, before any other character it is kept
+func ShellUnescape(b []byte) ([]byte, error) {
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		if b[i] != '\\' {
+			out = append(out, b[i])
+			continue
+		}
+		if i+1 >= len(b) {
+			return nil, escapeError(b, i, i+1, "escape at end of input")
+		}
+		switch b[i+1] {
+		case '$', '`', '"', '\\':
+			out = append(out, b[i+1])
+			i++
+		case '\n':
+			i++
+		default:
+			out = append(out, b[i])
+		}
+	}
+	return out, nil
+}
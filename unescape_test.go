@@ -0,0 +1,165 @@
+//
+// @author R. S. Doiel, <rsdoiel@gmail.com>
+//
+// Copyright (c) 2016, R. S. Doiel
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// * Redistributions of source code must retain the above copyright notice, this
+//   list of conditions and the following disclaimer.
+//
+// * Redistributions in binary form must reproduce the above copyright notice,
+//   this list of conditions and the following disclaimer in the documentation
+//   and/or other materials provided with the distribution.
+//
+// * Neither the name of tok nor the names of its
+//   contributors may be used to endorse or promote products derived from
+//   this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+// FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+// DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+// SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+// CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+// OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+//
+
+package tok
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCUnescape(t *testing.T) {
+	src := []byte(`a\"b\n\t\\\x41\101é\U0001F600\0`)
+	expected := "a\"b\n\t\\AAé😀\x00"
+	result, err := CUnescape(src)
+	if err != nil {
+		t.Errorf("unexpected error %s", err)
+	} else if string(result) != expected {
+		t.Errorf("expected %q, found %q", expected, result)
+	}
+
+	for src, offset := range map[string]int{
+		`ab\`:        2,
+		`a\q`:        1,
+		`\x4`:        0,
+		`a\xZZ`:      1,
+		`\u12`:       0,
+		`\uD800`:     0,
+		`x\777`:      1,
+		`\U00110000`: 0,
+	} {
+		_, err := CUnescape([]byte(src))
+		var escapeErr *EscapeError
+		if errors.As(err, &escapeErr) == false {
+			t.Errorf("expected *EscapeError for %q, found %v", src, err)
+		} else if escapeErr.Offset != offset {
+			t.Errorf("expected offset %d for %q, found %d (%s)", offset, src, escapeErr.Offset, err)
+		}
+	}
+}
+
+func TestJSONUnescape(t *testing.T) {
+	src := []byte(`\"a\/bé😀\n`)
+	expected := "\"a/bé😀\n"
+	result, err := JSONUnescape(src)
+	if err != nil {
+		t.Errorf("unexpected error %s", err)
+	} else if string(result) != expected {
+		t.Errorf("expected %q, found %q", expected, result)
+	}
+
+	for src, reason := range map[string]string{
+		`a\ud83d`:    "high surrogate is not followed by a low surrogate",
+		`\ud83dA`:    "high surrogate is not followed by a low surrogate",
+		`\ude00`:     "low surrogate without a high surrogate",
+		`\x41`:       "unknown escape",
+		`\u00g1`:     "\\u needs four hex digits",
+		`trailing \`: "escape at end of input",
+	} {
+		_, err := JSONUnescape([]byte(src))
+		var escapeErr *EscapeError
+		if errors.As(err, &escapeErr) == false {
+			t.Errorf("expected *EscapeError for %q, found %v", src, err)
+		} else if escapeErr.Reason != reason {
+			t.Errorf("expected %q for %q, found %q", reason, src, escapeErr.Reason)
+		}
+	}
+}
+
+func TestSQLAndShellUnescape(t *testing.T) {
+	result, err := SQLUnescape([]byte(`it''s ''quoted''`))
+	if err != nil || string(result) != `it's 'quoted'` {
+		t.Errorf("expected [it's 'quoted'], found [%s] %v", result, err)
+	}
+	if _, err := SQLUnescape([]byte(`it's`)); err == nil {
+		t.Errorf("expected an error for an undoubled quote")
+	}
+	result, err = DoubledQuoteUnescaper('"')([]byte(`say ""hi""`))
+	if err != nil || string(result) != `say "hi"` {
+		t.Errorf(`expected [say "hi"], found [%s] %v`, result, err)
+	}
+
+	result, err = ShellUnescape([]byte("\\$HOME \\\"x\\\" \\n a\\\nb \\\\"))
+	expected := "$HOME \"x\" \\n ab \\"
+	if err != nil || string(result) != expected {
+		t.Errorf("expected %q, found %q %v", expected, result, err)
+	}
+}
+
+func TestBetweenUnescape(t *testing.T) {
+	quote, escape := []byte(`"`), []byte(`\`)
+	between, buf, err := BetweenWith(quote, quote, escape, []byte(`x "a\"b\n" y`), BetweenOptions{Flat: true, Unescape: CUnescape})
+	if err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	if string(between) != "a\"b\n" {
+		t.Errorf("expected %q, found %q", "a\"b\n", between)
+	}
+	if string(buf) != " y" {
+		t.Errorf("expected [ y], found [%s]", buf)
+	}
+
+	_, _, err = BetweenWith(quote, quote, escape, []byte("\n \"ab\\q\""), BetweenOptions{Flat: true, Unescape: CUnescape})
+	var escapeErr *EscapeError
+	if errors.As(err, &escapeErr) == false {
+		t.Errorf("expected *EscapeError, found %v", err)
+	} else if escapeErr.Pos.Line != 2 || escapeErr.Pos.Column != 5 {
+		t.Errorf("expected escape at 2:5, found %s", escapeErr.Pos)
+	}
+
+	between, _, err = BetweenWith(quote, quote, escape, []byte(`"ok\q"`), BetweenOptions{Flat: true, Recover: true, Unescape: JSONUnescape})
+	if errors.As(err, &escapeErr) == false {
+		t.Errorf("expected *EscapeError in recovery mode, found %v", err)
+	}
+	if string(between) != `ok\q` {
+		t.Errorf("expected the raw content in recovery mode, found [%s]", between)
+	}
+}
+
+func TestBetweenSQLUnescape(t *testing.T) {
+	quote := []byte("'")
+	opts := BetweenOptions{DoubledClose: true, Unescape: SQLUnescape}
+	for src, expected := range map[string]string{
+		`x 'it''s' y`:      "it's",
+		`x '''quoted''' y`: "'quoted'",
+		`x '' y`:           "",
+		`x 'a''' y`:        "a'",
+	} {
+		between, buf, err := BetweenWith(quote, quote, nil, []byte(src), opts)
+		if err != nil || string(between) != expected || string(buf) != " y" {
+			t.Errorf("%s: expected [%s] then [ y], found [%s] then [%s] %v", src, expected, between, buf, err)
+		}
+	}
+	if between, _, _ := BetweenWith(quote, quote, nil, []byte(`'it''s'`), BetweenOptions{}); string(between) != "it" {
+		t.Errorf("expected a doubled quote to close the region without DoubledClose, found [%s]", between)
+	}
+}